@@ -0,0 +1,99 @@
+package main
+
+import (
+	"go/ast"
+	"go/doc"
+	"go/doc/comment"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+)
+
+/*** File and package doc comments ***/
+
+// packageDocs holds the doc comment of each package by import path, as
+// found by record.
+type packageDocs map[string]packageDoc
+
+type packageDoc struct {
+	text    string
+	fromDoc bool // taken from a doc_test.go file
+}
+
+// record notes the package doc comment found in any file of p. A doc_test.go
+// file takes precedence over the package under test's own doc comment, and
+// other test files are not considered, since their leading comments describe
+// the file.
+func (docs packageDocs) record(p *packages.Package) {
+	path := strings.TrimSuffix(p.PkgPath, "_test")
+	for _, node := range p.Syntax {
+		if node.Doc == nil {
+			continue
+		}
+		name := filepath.Base(p.Fset.File(node.Pos()).Name())
+		current := docs[path]
+		switch {
+		case name == "doc_test.go":
+			docs[path] = packageDoc{text: node.Doc.Text(), fromDoc: true}
+		case !strings.HasSuffix(name, "_test.go") && !current.fromDoc && current.text == "":
+			docs[path] = packageDoc{text: node.Doc.Text()}
+		}
+	}
+}
+
+// fileDescription returns the leading comment of a test file: the comment
+// above its package clause, or else the last comment before the package
+// clause that is not a build constraint or license header.
+func fileDescription(file *ast.File) string {
+	if file.Doc != nil {
+		return strings.TrimSpace(file.Doc.Text())
+	}
+	description := ""
+	for _, group := range file.Comments {
+		if group.End() > file.Package {
+			break
+		}
+		text := strings.TrimSpace(group.Text())
+		if text == "" || strings.HasPrefix(text, "+build") || strings.HasPrefix(text, "Copyright") || strings.Contains(text, "SPDX-License-Identifier") {
+			continue
+		}
+		description = text
+	}
+	return description
+}
+
+// docSummary returns the first sentence of a package doc comment.
+func docSummary(text string) string {
+	return new(doc.Package).Synopsis(text)
+}
+
+// docCommentBody returns a test comment without its tag lines.
+func docCommentBody(text string) string {
+	var lines []string
+	for _, line := range strings.Split(text, "\n") {
+		if !tagLine.MatchString(strings.TrimSpace(line)) {
+			lines = append(lines, line)
+		}
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+// hasDetails reports whether a test comment says more than the one-line
+// summary shown in the report table.
+func hasDetails(text string) bool {
+	return strings.Contains(docCommentBody(text), "\n")
+}
+
+// renderDocComment renders a Go doc comment as Markdown: paragraphs, lists,
+// code blocks, headings and doc links to standard library and other
+// packages, which link to pkg.go.dev. Tag lines are left out.
+func renderDocComment(text string) string {
+	var parser comment.Parser
+	printer := &comment.Printer{
+		DocLinkBaseURL: "https://pkg.go.dev",
+		HeadingLevel:   4,
+		HeadingID:      func(*comment.Heading) string { return "" },
+	}
+	return strings.TrimSpace(string(printer.Markdown(parser.Parse(docCommentBody(text)))))
+}
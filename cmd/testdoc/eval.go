@@ -0,0 +1,304 @@
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/constant"
+	"go/token"
+	"go/types"
+	"strconv"
+	"strings"
+)
+
+/*** Static evaluation of pure formatting calls in subtest names ***/
+
+// stringFuncs are the pure helpers evaluated when they appear in a subtest
+// name. Each receives its arguments already expanded to strings and reports
+// false when an argument cannot be interpreted (e.g. a non-numeric Itoa arg).
+var stringFuncs = map[string]func(args []string) (string, bool){
+	"strconv.Itoa": func(args []string) (string, bool) {
+		n, err := strconv.Atoi(args[0])
+		return strconv.Itoa(n), err == nil
+	},
+	"strconv.FormatInt": func(args []string) (string, bool) {
+		n, err := strconv.ParseInt(args[0], 0, 64)
+		if err != nil {
+			return "", false
+		}
+		base, err := strconv.Atoi(args[1])
+		if err != nil || base < 2 || base > 36 {
+			return "", false
+		}
+		return strconv.FormatInt(n, base), true
+	},
+	"strconv.Quote": func(args []string) (string, bool) {
+		return strconv.Quote(args[0]), true
+	},
+	"strings.ToLower": func(args []string) (string, bool) {
+		return strings.ToLower(args[0]), true
+	},
+	"strings.ToUpper": func(args []string) (string, bool) {
+		return strings.ToUpper(args[0]), true
+	},
+	"strings.Repeat": func(args []string) (string, bool) {
+		n, err := strconv.Atoi(args[1])
+		if err != nil || n < 0 {
+			return "", false
+		}
+		return strings.Repeat(args[0], n), true
+	},
+}
+
+// stringFuncArity is the number of arguments each entry of stringFuncs takes.
+var stringFuncArity = map[string]int{
+	"strconv.Itoa":      1,
+	"strconv.FormatInt": 2,
+	"strconv.Quote":     1,
+	"strings.ToLower":   1,
+	"strings.ToUpper":   1,
+	"strings.Repeat":    2,
+}
+
+// evalCall statically evaluates fmt.Sprintf, strings.Join and the helpers in
+// stringFuncs, producing one result per combination of expanded argument
+// values. It reports false when the call is not one it knows how to evaluate.
+func evalCall(pkg *sourcePackage, call *ast.CallExpr, expandedVariables []ExpandedVar) ([]string, bool) {
+	if call.Ellipsis.IsValid() {
+		return nil, false
+	}
+	name := pkg.calleeName(call)
+
+	switch name {
+	case "fmt.Sprintf":
+		return evalSprintf(pkg, call, expandedVariables)
+
+	case "strings.Join":
+		if len(call.Args) != 2 {
+			return nil, false
+		}
+		elems := pkg.resolveCompositeLit(call.Args[0])
+		if elems == nil {
+			return nil, false
+		}
+		args := make([][]string, 0, len(elems.Elts)+1)
+		for _, elt := range elems.Elts {
+			args = append(args, expandTestName(pkg, elt, expandedVariables))
+		}
+		args = append(args, expandTestName(pkg, call.Args[1], expandedVariables))
+		var results []string
+		for _, combo := range combinations(args) {
+			results = append(results, strings.Join(combo[:len(combo)-1], combo[len(combo)-1]))
+		}
+		return results, true
+	}
+
+	fn, ok := stringFuncs[name]
+	if !ok || len(call.Args) != stringFuncArity[name] {
+		return nil, false
+	}
+	args := make([][]string, len(call.Args))
+	for i, arg := range call.Args {
+		args[i] = expandTestName(pkg, arg, expandedVariables)
+	}
+	var results []string
+	for _, combo := range combinations(args) {
+		s, ok := fn(combo)
+		if !ok {
+			return nil, false
+		}
+		results = append(results, s)
+	}
+	return results, true
+}
+
+// evalSprintf formats every combination of argument values with the call's
+// format string. Arguments are converted back to the Go type the format verb
+// expects, using type information when available and the verb otherwise.
+// Calls with arguments fmt prints through their own methods, such as
+// fmt.Stringer and error values, or of non-basic types have no static value.
+func evalSprintf(pkg *sourcePackage, call *ast.CallExpr, expandedVariables []ExpandedVar) ([]string, bool) {
+	if len(call.Args) == 0 {
+		return nil, false
+	}
+	formats := expandTestName(pkg, call.Args[0], expandedVariables)
+	if len(formats) != 1 {
+		return nil, false
+	}
+	format := formats[0]
+	verbs, ok := formatVerbs(format)
+	if !ok || len(verbs) != len(call.Args)-1 {
+		return nil, false
+	}
+
+	args := make([][]string, len(call.Args)-1)
+	for i, arg := range call.Args[1:] {
+		// Stringers and errors print their own text, not their value
+		if !pkg.formatsAsValue(arg) {
+			return nil, false
+		}
+		args[i] = expandTestName(pkg, arg, expandedVariables)
+	}
+
+	var results []string
+	for _, combo := range combinations(args) {
+		typed := make([]interface{}, len(combo))
+		for i, value := range combo {
+			v, ok := typedFormatArg(pkg.basicInfo(call.Args[i+1]), verbs[i], value)
+			if !ok {
+				return nil, false
+			}
+			typed[i] = v
+		}
+		results = append(results, fmt.Sprintf(format, typed...))
+	}
+	return results, true
+}
+
+// evalBinary evaluates string concatenation and integer +, - and * for
+// every combination of expanded operand values. Without type information
+// only concatenation with a string literal operand is evaluated; integer
+// results that overflow their type report false.
+func evalBinary(pkg *sourcePackage, e *ast.BinaryExpr, expandedVariables []ExpandedVar) ([]string, bool) {
+	var basic *types.Basic
+	if pkg != nil && pkg.info != nil {
+		if t := pkg.info.TypeOf(e); t != nil {
+			basic, _ = t.Underlying().(*types.Basic)
+		}
+	}
+
+	var op func(x, y string) (string, bool)
+	switch {
+	case basic == nil:
+		if e.Op != token.ADD || !isStringLit(e.X) && !isStringLit(e.Y) {
+			return nil, false
+		}
+		op = func(x, y string) (string, bool) { return x + y, true }
+	case basic.Info()&types.IsString != 0 && e.Op == token.ADD:
+		op = func(x, y string) (string, bool) { return x + y, true }
+	case basic.Info()&types.IsInteger != 0 && (e.Op == token.ADD || e.Op == token.SUB || e.Op == token.MUL):
+		op = func(x, y string) (string, bool) { return evalIntOp(e.Op, x, y, basic) }
+	default:
+		return nil, false
+	}
+
+	left := expandTestName(pkg, e.X, expandedVariables)
+	right := expandTestName(pkg, e.Y, expandedVariables)
+	var results []string
+	for _, l := range left {
+		for _, r := range right {
+			v, ok := op(l, r)
+			if !ok {
+				return nil, false
+			}
+			results = append(results, v)
+		}
+	}
+	return results, true
+}
+
+// evalIntOp applies an integer operator to two expanded values of type t,
+// reporting false if either is not an integer or the result overflows t.
+func evalIntOp(op token.Token, x, y string, t *types.Basic) (string, bool) {
+	xv, yv := parseIntValue(x), parseIntValue(y)
+	if xv.Kind() != constant.Int || yv.Kind() != constant.Int {
+		return "", false
+	}
+	result := constant.BinaryOp(xv, op, yv)
+
+	if t.Info()&types.IsUntyped == 0 {
+		bits := uint(types.SizesFor("gc", "amd64").Sizeof(t) * 8)
+		lo, hi := constant.MakeInt64(0), constant.Shift(constant.MakeInt64(1), token.SHL, bits)
+		if t.Info()&types.IsUnsigned == 0 {
+			lo = constant.UnaryOp(token.SUB, constant.Shift(constant.MakeInt64(1), token.SHL, bits-1), 0)
+			hi = constant.Shift(constant.MakeInt64(1), token.SHL, bits-1)
+		}
+		if constant.Compare(result, token.LSS, lo) || constant.Compare(result, token.GEQ, hi) {
+			return "", false
+		}
+	}
+	return result.ExactString(), true
+}
+
+// parseIntValue parses an expanded integer value, which unlike a Go literal may
+// be negative. It is an unknown value if s is not an integer.
+func parseIntValue(s string) constant.Value {
+	if digits, ok := strings.CutPrefix(s, "-"); ok {
+		return constant.UnaryOp(token.SUB, constant.MakeFromLiteral(digits, token.INT, 0), 0)
+	}
+	return constant.MakeFromLiteral(s, token.INT, 0)
+}
+
+// isStringLit reports whether expr is a string literal.
+func isStringLit(expr ast.Expr) bool {
+	lit, ok := expr.(*ast.BasicLit)
+	return ok && lit.Kind == token.STRING
+}
+
+// formatVerbs returns the verb of every argument-consuming directive in a
+// Printf format string. Explicit argument indexes and '*' widths are not
+// supported and report false.
+func formatVerbs(format string) ([]rune, bool) {
+	var verbs []rune
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' {
+			continue
+		}
+		i++
+		for i < len(format) && strings.ContainsRune("+-# 0123456789.", rune(format[i])) {
+			i++
+		}
+		if i >= len(format) {
+			return nil, false
+		}
+		switch format[i] {
+		case '%':
+			continue
+		case '[', '*':
+			return nil, false
+		}
+		verbs = append(verbs, rune(format[i]))
+	}
+	return verbs, true
+}
+
+// typedFormatArg turns an expanded argument value back into a value of the
+// kind fmt expects. info is the argument's basic type info, or 0 if unknown.
+func typedFormatArg(info types.BasicInfo, verb rune, value string) (interface{}, bool) {
+	integer := info&types.IsInteger != 0
+	float := info&types.IsFloat != 0
+	boolean := info&types.IsBoolean != 0
+	if info == 0 {
+		integer = strings.ContainsRune("dboOxXcU", verb)
+		float = strings.ContainsRune("eEfFgG", verb)
+		boolean = verb == 't'
+	}
+
+	switch {
+	case integer:
+		n, err := strconv.ParseInt(value, 0, 64)
+		return n, err == nil
+	case float:
+		f, err := strconv.ParseFloat(value, 64)
+		return f, err == nil
+	case boolean:
+		b, err := strconv.ParseBool(value)
+		return b, err == nil
+	}
+	return value, true
+}
+
+// combinations returns the cartesian product of the given value lists.
+func combinations(lists [][]string) [][]string {
+	results := [][]string{{}}
+	for _, list := range lists {
+		var next [][]string
+		for _, prefix := range results {
+			for _, value := range list {
+				combo := append(append([]string{}, prefix...), value)
+				next = append(next, combo)
+			}
+		}
+		results = next
+	}
+	return results
+}
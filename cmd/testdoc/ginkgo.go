@@ -0,0 +1,163 @@
+package main
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+	"strings"
+)
+
+/*** Ginkgo spec trees ***/
+
+var ginkgoPackages = map[string]bool{
+	"github.com/onsi/ginkgo":    true,
+	"github.com/onsi/ginkgo/v2": true,
+}
+
+// Ginkgo container nodes, including their focused (F), pending (P) and
+// excluded (X) variants.
+var ginkgoContainers = map[string]bool{
+	"Describe": true, "FDescribe": true, "PDescribe": true, "XDescribe": true,
+	"Context": true, "FContext": true, "PContext": true, "XContext": true,
+	"When": true, "FWhen": true, "PWhen": true, "XWhen": true,
+	"DescribeTable": true, "FDescribeTable": true, "PDescribeTable": true, "XDescribeTable": true,
+}
+
+// Ginkgo leaf nodes. Table entries are reported as It nodes.
+var ginkgoLeaves = map[string]bool{
+	"It": true, "FIt": true, "PIt": true, "XIt": true,
+	"Specify": true, "FSpecify": true, "PSpecify": true, "XSpecify": true,
+	"Entry": true, "FEntry": true, "PEntry": true, "XEntry": true,
+}
+
+// ginkgoFunc returns the name of the Ginkgo function a call invokes, whether
+// it is dot-imported or qualified, or "" if it is not a Ginkgo call.
+func (sp *sourcePackage) ginkgoFunc(call *ast.CallExpr) string {
+	var ident *ast.Ident
+	switch fun := call.Fun.(type) {
+	case *ast.Ident:
+		ident = fun
+	case *ast.SelectorExpr:
+		ident = fun.Sel
+	default:
+		return ""
+	}
+	if sp != nil && sp.info != nil {
+		fn, ok := sp.info.Uses[ident].(*types.Func)
+		if !ok || fn.Pkg() == nil || !ginkgoPackages[fn.Pkg().Path()] {
+			return ""
+		}
+	}
+	return ident.Name
+}
+
+// ginkgoSuiteDescription returns the description passed to RunSpecs, which
+// Ginkgo's JUnit reporter uses as the classname of every spec.
+func (sp *sourcePackage) ginkgoSuiteDescription(call *ast.CallExpr) (string, bool) {
+	if sp == nil || sp.info == nil || sp.ginkgoFunc(call) != "RunSpecs" || len(call.Args) < 2 {
+		return "", false
+	}
+	return firstValue(expandTestName(sp, call.Args[1], nil)), true
+}
+
+// CollectGinkgoSpecs builds the Describe/Context/It tree declared at package
+// level (`var _ = Describe(...)`) in any file of the package. Leaf specs are
+// named like Ginkgo's JUnit reporter names them:
+// "[It] <container texts> <spec text> [labels]", with classname description.
+func CollectGinkgoSpecs(pkg *sourcePackage, description string) []TestUnit {
+	specs := make([]TestUnit, 0)
+	for _, f := range pkg.files {
+		for _, decl := range f.Decls {
+			gen, ok := decl.(*ast.GenDecl)
+			if !ok || gen.Tok != token.VAR {
+				continue
+			}
+			for _, spec := range gen.Specs {
+				for _, value := range spec.(*ast.ValueSpec).Values {
+					specs = append(specs, pkg.collectGinkgoNodes(value, description, nil, nil, nil)...)
+				}
+			}
+		}
+	}
+	return specs
+}
+
+// collectGinkgoNodes collects the Ginkgo nodes started within n, nested under
+// the container texts and labels of its ancestors.
+func (sp *sourcePackage) collectGinkgoNodes(n ast.Node, description string, texts, labels []string, expandedVariables []ExpandedVar) []TestUnit {
+	nodes := make([]TestUnit, 0)
+
+	ast.Inspect(n, func(node ast.Node) bool {
+		if loop, ok := node.(*ast.RangeStmt); ok {
+			astFile := sp.fileAt(loop.Pos())
+			file := sp.fset.File(loop.Pos())
+			for _, iteration := range rangeIterations(sp, loop, astFile.Comments, file, file.Name(), expandedVariables) {
+				nodes = append(nodes, sp.collectGinkgoNodes(loop.Body, description, texts, labels, iteration.vars)...)
+			}
+			return false
+		}
+
+		call, ok := node.(*ast.CallExpr)
+		if !ok {
+			return true
+		}
+		name := sp.ginkgoFunc(call)
+		if !ginkgoContainers[name] && !ginkgoLeaves[name] {
+			return true
+		}
+		if len(call.Args) == 0 {
+			return false
+		}
+
+		text := firstValue(expandTestName(sp, call.Args[0], expandedVariables))
+		nodeTexts := append(append([]string{}, texts...), text)
+		nodeLabels := append(append([]string{}, labels...), sp.ginkgoLabels(call.Args[1:], expandedVariables)...)
+
+		astFile := sp.fileAt(call.Pos())
+		file := sp.fset.File(call.Pos())
+		unit := TestUnit{
+			CommentHeader: FindRelativeComment(call.Pos(), astFile.Comments, file, file.Name()),
+			TestName:      text,
+			ClassName:     description,
+		}
+		unit.File, unit.Line = sp.position(call.Pos())
+
+		if ginkgoLeaves[name] {
+			unit.MachineTestName = "[It] " + strings.Join(nodeTexts, " ")
+			if len(nodeLabels) > 0 {
+				unit.MachineTestName += " [" + strings.Join(nodeLabels, ", ") + "]"
+			}
+		} else {
+			unit.MachineTestName = strings.Join(nodeTexts, " ")
+			unit.Container = true
+			for _, arg := range call.Args[1:] {
+				unit.Subtests = append(unit.Subtests, sp.collectGinkgoNodes(arg, description, nodeTexts, nodeLabels, expandedVariables)...)
+			}
+		}
+		nodes = append(nodes, unit)
+		return false
+	})
+	return nodes
+}
+
+// ginkgoLabels returns the labels attached to a node with Label(...) decorators.
+func (sp *sourcePackage) ginkgoLabels(args []ast.Expr, expandedVariables []ExpandedVar) []string {
+	var labels []string
+	for _, arg := range args {
+		call, ok := arg.(*ast.CallExpr)
+		if !ok || sp.ginkgoFunc(call) != "Label" {
+			continue
+		}
+		for _, labelArg := range call.Args {
+			labels = append(labels, firstValue(expandTestName(sp, labelArg, expandedVariables)))
+		}
+	}
+	return labels
+}
+
+func firstValue(values []string) string {
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+/*** go test -json (test2json) parsing ***/
+
+// testEvent is a line of the test2json stream `go test -json` writes.
+type testEvent struct {
+	Action  string
+	Package string
+	Test    string
+	Elapsed float64
+	Output  string
+}
+
+// ParseGoTestJSONFile parses a `go test -json` stream from path, or from
+// standard input if path is "-".
+func ParseGoTestJSONFile(path string) (map[string]junitRecord, error) {
+	if path == "-" {
+		return ParseGoTestJSON(os.Stdin)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ParseGoTestJSON(f)
+}
+
+// ParseGoTestJSON reads the run/pass/fail/skip/output events of a
+// `go test -json` stream into the same records ParseJUnitResults returns,
+// keyed by package and test name. The output of a failed or skipped test,
+// without the === RUN and --- FAIL framing lines, becomes its failure text.
+// Tests that started but never finished, as when their package panicked or
+// timed out, are recorded as failed. Lines that are not JSON events, such as
+// build errors, are skipped; a stream without any event, as from a closed
+// stdin, is an error.
+func ParseGoTestJSON(r io.Reader) (map[string]junitRecord, error) {
+	out := map[string]junitRecord{}
+	output := map[string][]string{}
+	var started []string
+	failedPkgs := map[string]bool{}
+	events := 0
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(line, "{") {
+			continue
+		}
+		var ev testEvent
+		if err := json.Unmarshal([]byte(line), &ev); err != nil {
+			continue
+		}
+		events++
+		if ev.Test == "" {
+			if ev.Action == "fail" {
+				failedPkgs[ev.Package] = true
+			}
+			continue
+		}
+
+		key := pkgKey(ev.Package, ev.Test)
+		switch ev.Action {
+		case "run":
+			started = append(started, key)
+		case "output":
+			if !isFramingLine(ev.Output) {
+				output[key] = append(output[key], strings.TrimRight(ev.Output, "\n"))
+			}
+		case "pass", "fail", "skip":
+			rec := junitRecord{
+				Status:   strings.ToUpper(ev.Action),
+				Duration: formatSeconds(ev.Elapsed),
+				Seconds:  ev.Elapsed,
+			}
+			if ev.Action != "pass" {
+				rec.Failure = strings.TrimSpace(strings.Join(output[key], "\n"))
+			}
+			addAttempt(out, key, rec)
+			delete(output, key)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read go test -json stream: %v", err)
+	}
+	if events == 0 {
+		return nil, fmt.Errorf("no go test -json events found")
+	}
+
+	for _, key := range started {
+		if _, ok := out[key]; ok {
+			continue
+		}
+		pkg, _, _ := strings.Cut(key, "::")
+		if failedPkgs[pkg] {
+			out[key] = junitRecord{
+				Status:  "FAIL",
+				Failure: strings.TrimSpace(strings.Join(output[key], "\n")),
+			}
+		}
+	}
+	return out, nil
+}
+
+// isFramingLine reports whether a test output line is one the testing
+// package prints around a test's own output, like "=== RUN   TestX" or
+// "--- FAIL: TestX (0.00s)".
+func isFramingLine(line string) bool {
+	line = strings.TrimSpace(line)
+	for _, prefix := range []string{"=== RUN", "=== PAUSE", "=== CONT", "=== NAME", "--- PASS:", "--- FAIL:", "--- SKIP:"} {
+		if strings.HasPrefix(line, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// stdinIsPipe reports whether standard input is redirected from a pipe or
+// file rather than a terminal, as in `go test -json ./... | testdoc`.
+func stdinIsPipe() bool {
+	info, err := os.Stdin.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice == 0
+}
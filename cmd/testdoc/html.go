@@ -0,0 +1,41 @@
+package main
+
+import (
+	_ "embed"
+	"fmt"
+	"html/template"
+	"os"
+	"path/filepath"
+)
+
+/*** Standalone HTML report ***/
+
+//go:embed templates/report.html.tmpl
+var htmlReportTemplate string
+
+// GenerateHTMLReport writes the report model as a single self-contained HTML
+// page, with inline CSS and JavaScript and no external resources. Each suite
+// is a collapsible tree of its tests, which can be filtered by status and
+// searched by name and description; failure details expand in place.
+func GenerateHTMLReport(testSuites []TestSuite, jmap map[string]junitRecord, outPath string, opts ReportOptions) error {
+	if abs, err := filepath.Abs(outPath); err == nil {
+		opts.reportDir = filepath.Dir(abs)
+	}
+
+	data := buildTemplateData(testSuites, jmap, opts)
+	tmpl, err := template.New("report").Funcs(templateFuncs(data)).Parse(htmlReportTemplate)
+	if err != nil {
+		return fmt.Errorf("error parsing HTML template: %v", err)
+	}
+
+	f, err := os.Create(outPath)
+	if err != nil {
+		return fmt.Errorf("error creating output file: %v", err)
+	}
+	defer f.Close()
+
+	if err := tmpl.Execute(f, data); err != nil {
+		return fmt.Errorf("error rendering HTML report: %v", err)
+	}
+	return nil
+}
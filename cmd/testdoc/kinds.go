@@ -0,0 +1,127 @@
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/doc"
+	"go/token"
+	"go/types"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+/*** Benchmark, Fuzz and Example functions ***/
+
+// Kinds of top-level test functions, named after their go test prefix.
+const (
+	KindTest      = "Test"
+	KindBenchmark = "Benchmark"
+	KindFuzz      = "Fuzz"
+	KindExample   = "Example"
+)
+
+// testKinds lists the kinds in the order their report sections appear.
+var testKinds = []string{KindTest, KindBenchmark, KindFuzz, KindExample}
+
+var kindSectionTitles = map[string]string{
+	KindTest:      "Tests",
+	KindBenchmark: "Benchmarks",
+	KindFuzz:      "Fuzz Tests",
+	KindExample:   "Examples",
+}
+
+// TestFuncKind reports which kind of test function go test considers name to
+// be, or "" if it is none. As in go test, the prefix must be followed by the
+// end of the name or a non-lowercase letter, and TestMain is not a test.
+func TestFuncKind(name string) string {
+	if name == "TestMain" {
+		return ""
+	}
+	for _, kind := range testKinds {
+		if !strings.HasPrefix(name, kind) {
+			continue
+		}
+		if len(name) == len(kind) {
+			return kind
+		}
+		r, _ := utf8.DecodeRuneInString(name[len(kind):])
+		if !unicode.IsLower(r) {
+			return kind
+		}
+	}
+	return ""
+}
+
+// exampleOutputs maps the Example functions of a file to their expected
+// output, as declared by a trailing "// Output:" or "// Unordered output:"
+// comment.
+func exampleOutputs(file *ast.File) map[string]string {
+	outputs := make(map[string]string)
+	for _, ex := range doc.Examples(file) {
+		if ex.Output == "" && !ex.EmptyOutput {
+			continue
+		}
+		outputs["Example"+ex.Name] = ex.Output
+	}
+	return outputs
+}
+
+// CollectFuzzSeeds lists the seed corpus entries a fuzz test adds with
+// f.Add. go test runs each of them as a subtest named "seed#N".
+func CollectFuzzSeeds(pkg *sourcePackage, fuzzBody *ast.BlockStmt, comments []*ast.CommentGroup, file *token.File, filePath string, parentName string) []TestUnit {
+	seeds := make([]TestUnit, 0)
+
+	addSeeds := func(call *ast.CallExpr, expandedVariables []ExpandedVar) {
+		args := make([][]string, len(call.Args))
+		for i, arg := range call.Args {
+			values := expandTestName(pkg, arg, expandedVariables)
+			if pkg.basicInfo(arg)&types.IsString != 0 {
+				for j, v := range values {
+					values[j] = strconv.Quote(v)
+				}
+			}
+			args[i] = values
+		}
+		comment := FindRelativeComment(call.Pos(), comments, file, filePath)
+		seedFile, line := pkg.position(call.Pos())
+		for _, combo := range combinations(args) {
+			seedName := fmt.Sprintf("seed#%d", len(seeds))
+			seeds = append(seeds, TestUnit{
+				Kind:            KindFuzz,
+				CommentHeader:   comment,
+				MachineTestName: parentName + "/" + seedName,
+				TestName:        fmt.Sprintf("%s (%s)", seedName, strings.Join(combo, ", ")),
+				File:            seedFile,
+				Line:            line,
+			})
+		}
+	}
+
+	ast.Inspect(fuzzBody, func(n ast.Node) bool {
+		if loop, ok := n.(*ast.RangeStmt); ok {
+			for _, iteration := range rangeIterations(pkg, loop, comments, file, filePath, nil) {
+				ast.Inspect(loop.Body, func(innerN ast.Node) bool {
+					if call, ok := innerN.(*ast.CallExpr); ok && isFuzzAdd(call) {
+						addSeeds(call, iteration.vars)
+						return false
+					}
+					return true
+				})
+			}
+			return false
+		}
+		if call, ok := n.(*ast.CallExpr); ok && isFuzzAdd(call) {
+			addSeeds(call, nil)
+			return false
+		}
+		return true
+	})
+	return seeds
+}
+
+func isFuzzAdd(call *ast.CallExpr) bool {
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	return ok && sel.Sel != nil && sel.Sel.Name == "Add" && len(call.Args) > 0
+}
@@ -0,0 +1,299 @@
+package main
+
+import (
+	"go/ast"
+	"go/token"
+	"strconv"
+)
+
+/*** Loop unrolling for parameterized subtests ***/
+
+// loopIteration is one pass over a loop body: the variables bound for that
+// pass and, for table-driven tests, the comment above and position of the
+// table entry.
+type loopIteration struct {
+	vars    []ExpandedVar
+	comment string
+	pos     token.Pos
+}
+
+// within returns the iteration nested in an iteration of an enclosing loop,
+// inheriting the outer table entry's comment and position when it has none.
+func (it loopIteration) within(outer loopIteration) loopIteration {
+	if it.comment == "" {
+		it.comment = outer.comment
+	}
+	if !it.pos.IsValid() {
+		it.pos = outer.pos
+	}
+	return it
+}
+
+// lookupVar finds the innermost binding of name, so that loop variables
+// shadow those of enclosing loops.
+func lookupVar(expandedVariables []ExpandedVar, name string) (ExpandedVar, bool) {
+	for i := len(expandedVariables) - 1; i >= 0; i-- {
+		if expandedVariables[i].VarName == name {
+			return expandedVariables[i], true
+		}
+	}
+	return ExpandedVar{}, false
+}
+
+// bindVar returns a copy of expandedVariables with name bound to values.
+// Blank and missing loop variables are not bound.
+func bindVar(expandedVariables []ExpandedVar, name string, values []string) []ExpandedVar {
+	if name == "" || name == "_" {
+		return expandedVariables
+	}
+	vars := make([]ExpandedVar, len(expandedVariables), len(expandedVariables)+1)
+	copy(vars, expandedVariables)
+	return append(vars, ExpandedVar{VarName: name, VarValue: values})
+}
+
+func identName(expr ast.Expr) string {
+	if ident, ok := expr.(*ast.Ident); ok {
+		return ident.Name
+	}
+	return ""
+}
+
+// rangeIterations unrolls a range loop into one iteration per element,
+// binding both the key and the value of each. Tables of struct literals bind
+// every field as "<loopvar>.<field>" and carry the comment above the entry;
+// maps bind their keys; integer ranges like `for i := range 3` bind the index.
+// A loop over something that cannot be resolved runs once with its variables
+// bound to their own names, so they still shadow those of enclosing loops.
+func rangeIterations(pkg *sourcePackage, loop *ast.RangeStmt, comments []*ast.CommentGroup, file *token.File, filePath string, expandedVariables []ExpandedVar) []loopIteration {
+	keyName := identName(loop.Key)
+	valueName := identName(loop.Value)
+	var iterations []loopIteration
+
+	if entries := ExtractTableEntries(pkg, loop); len(entries) > 0 {
+		for i, entry := range entries {
+			key := []string{strconv.Itoa(i)}
+			if entry.Key != nil {
+				key = expandTestName(pkg, entry.Key, expandedVariables)
+			}
+			vars := bindVar(expandedVariables, keyName, key)
+			if valueName != "" {
+				for _, field := range entry.Fields {
+					vars = bindVar(vars, valueName+"."+field.Name, expandTestName(pkg, field.Value, expandedVariables))
+				}
+			}
+			iterations = append(iterations, loopIteration{
+				vars:    vars,
+				comment: pkg.commentAbove(entry.Pos, comments, file, filePath),
+				pos:     entry.Pos,
+			})
+		}
+		return iterations
+	}
+
+	if comp := pkg.resolveCompositeLit(loop.X); comp != nil {
+		for i, elt := range comp.Elts {
+			key := []string{strconv.Itoa(i)}
+			if kv, ok := elt.(*ast.KeyValueExpr); ok {
+				key = expandTestName(pkg, kv.Key, expandedVariables)
+				elt = kv.Value
+			}
+			vars := bindVar(expandedVariables, keyName, key)
+			vars = bindVar(vars, valueName, expandTestName(pkg, elt, expandedVariables))
+			iterations = append(iterations, loopIteration{vars: vars})
+		}
+		return iterations
+	}
+
+	if n, ok := intValue(pkg, loop.X, expandedVariables); ok && n <= MAX_LOOP_ITERATIONS {
+		for i := 0; i < n; i++ {
+			iterations = append(iterations, loopIteration{vars: bindVar(expandedVariables, keyName, []string{strconv.Itoa(i)})})
+		}
+		return iterations
+	}
+
+	vars := bindVar(expandedVariables, keyName, []string{keyName})
+	vars = bindVar(vars, valueName, []string{valueName})
+	return []loopIteration{{vars: vars}}
+}
+
+// forIterations unrolls counting loops with constant bounds such as
+// `for i := 0; i < 3; i++` or `for i := 10; i > 0; i -= 2`. Any other loop
+// runs once with its loop variable bound to its own name.
+func forIterations(pkg *sourcePackage, loop *ast.ForStmt, expandedVariables []ExpandedVar) []loopIteration {
+	var varName string
+	if init, ok := loop.Init.(*ast.AssignStmt); ok && init.Tok == token.DEFINE && len(init.Lhs) == 1 && len(init.Rhs) == 1 {
+		varName = identName(init.Lhs[0])
+		if start, ok := intValue(pkg, init.Rhs[0], expandedVariables); ok {
+			if values, ok := countingLoopValues(pkg, loop, varName, start, expandedVariables); ok {
+				iterations := make([]loopIteration, 0, len(values))
+				for _, i := range values {
+					iterations = append(iterations, loopIteration{vars: bindVar(expandedVariables, varName, []string{strconv.Itoa(i)})})
+				}
+				return iterations
+			}
+		}
+	}
+	return []loopIteration{{vars: bindVar(expandedVariables, varName, []string{varName})}}
+}
+
+// countingLoopValues simulates the condition and post statement of a
+// counting loop starting at start, reporting false if either is not a
+// constant comparison or step of the loop variable.
+func countingLoopValues(pkg *sourcePackage, loop *ast.ForStmt, varName string, start int, expandedVariables []ExpandedVar) ([]int, bool) {
+	cond, ok := loop.Cond.(*ast.BinaryExpr)
+	if !ok || identName(cond.X) != varName {
+		return nil, false
+	}
+	bound, ok := intValue(pkg, cond.Y, expandedVariables)
+	if !ok {
+		return nil, false
+	}
+
+	var step int
+	switch post := loop.Post.(type) {
+	case *ast.IncDecStmt:
+		if identName(post.X) != varName {
+			return nil, false
+		}
+		step = 1
+		if post.Tok == token.DEC {
+			step = -1
+		}
+	case *ast.AssignStmt:
+		if len(post.Lhs) != 1 || len(post.Rhs) != 1 || identName(post.Lhs[0]) != varName {
+			return nil, false
+		}
+		if step, ok = intValue(pkg, post.Rhs[0], expandedVariables); !ok {
+			return nil, false
+		}
+		switch post.Tok {
+		case token.ADD_ASSIGN:
+		case token.SUB_ASSIGN:
+			step = -step
+		default:
+			return nil, false
+		}
+	default:
+		return nil, false
+	}
+
+	var values []int
+	for i := start; ; i += step {
+		var more bool
+		switch cond.Op {
+		case token.LSS:
+			more = i < bound
+		case token.LEQ:
+			more = i <= bound
+		case token.GTR:
+			more = i > bound
+		case token.GEQ:
+			more = i >= bound
+		case token.NEQ:
+			more = i != bound
+		default:
+			return nil, false
+		}
+		if !more {
+			return values, true
+		}
+		if len(values) == MAX_LOOP_ITERATIONS {
+			return nil, false
+		}
+		values = append(values, i)
+	}
+}
+
+// intValue evaluates expr to a single integer, using constants and the
+// values bound to enclosing loop variables.
+func intValue(pkg *sourcePackage, expr ast.Expr, expandedVariables []ExpandedVar) (int, bool) {
+	if _, ok := expr.(*ast.CompositeLit); ok {
+		return 0, false
+	}
+	values := expandTestName(pkg, expr, expandedVariables)
+	if len(values) != 1 {
+		return 0, false
+	}
+	n, err := strconv.Atoi(values[0])
+	return n, err == nil
+}
+
+// TableEntry is a single struct literal in a table-driven test's case list.
+// Key is the map key of the entry for tables declared as maps.
+type TableEntry struct {
+	Pos    token.Pos
+	Key    ast.Expr
+	Fields []TableField
+}
+
+// TableField is a field of a table entry, by name, with its value expression.
+type TableField struct {
+	Name  string
+	Value ast.Expr
+}
+
+// ExtractTableEntries resolves the range expression of a table-driven test
+// (`for _, tt := range tests`) to its declared slice or map of struct
+// literals and returns one entry per element. Both keyed and positional
+// struct literals are supported; positional fields are named from the struct
+// type declaration, and fields of basic type a keyed literal omits are bound
+// to their zero value.
+func ExtractTableEntries(pkg *sourcePackage, rangeStmt *ast.RangeStmt) []TableEntry {
+	comp := pkg.resolveCompositeLit(rangeStmt.X)
+	if comp == nil {
+		return nil
+	}
+
+	var elemType ast.Expr
+	switch t := comp.Type.(type) {
+	case *ast.ArrayType:
+		elemType = t.Elt
+	case *ast.MapType:
+		elemType = t.Value
+	}
+
+	var entries []TableEntry
+	for _, elt := range comp.Elts {
+		entry := TableEntry{Pos: elt.Pos()}
+		if kv, ok := elt.(*ast.KeyValueExpr); ok {
+			entry.Key = kv.Key
+			elt = kv.Value
+		}
+		if unary, ok := elt.(*ast.UnaryExpr); ok && unary.Op == token.AND {
+			elt = unary.X
+		}
+		lit, ok := elt.(*ast.CompositeLit)
+		if !ok {
+			return nil
+		}
+		names := pkg.tableFieldNames(lit, elemType)
+		if names == nil {
+			return nil
+		}
+
+		set := make(map[string]bool)
+		for i, fieldElt := range lit.Elts {
+			if kv, ok := fieldElt.(*ast.KeyValueExpr); ok {
+				if key, ok := kv.Key.(*ast.Ident); ok {
+					entry.Fields = append(entry.Fields, TableField{Name: key.Name, Value: kv.Value})
+					set[key.Name] = true
+				}
+				continue
+			}
+			if i < len(names) {
+				entry.Fields = append(entry.Fields, TableField{Name: names[i], Value: fieldElt})
+				set[names[i]] = true
+			}
+		}
+
+		// Fields a keyed literal leaves out hold their zero value
+		zeros := pkg.tableFieldZeros(lit, elemType)
+		for _, name := range names {
+			if zero, ok := zeros[name]; ok && !set[name] {
+				entry.Fields = append(entry.Fields, TableField{Name: name, Value: zero})
+			}
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
@@ -2,16 +2,22 @@ package main
 
 import (
 	"encoding/xml"
+	"errors"
 	"flag"
 	"fmt"
 	"go/ast"
 	"go/format"
-	"go/parser"
 	"go/token"
+	"math"
+	"net/url"
 	"os"
+	"path"
 	"path/filepath"
+	"regexp"
+	"sort"
 	"strconv"
 	"strings"
+	"unicode/utf8"
 
 	"golang.org/x/tools/go/packages"
 )
@@ -20,24 +26,57 @@ import (
 var (
 	sourceDir      string
 	outPath        string
-	junitPath      string
+	outFormat      string
+	junitPaths     stringList
+	goTestJSON     string
 	failSnippetMax int
+	linkBase       string
+	linkSHA        string
+	traceMode      bool
+	groupByPackage bool
+	docDetails     bool
+	reqPath        string
+	templatePath   string
+	nestedLayout   bool
+	slowestN       int
+	githubOutput   string
 )
 
 type TestSuite struct {
 	PackageName   string
+	PackageDoc    string // doc comment of the package under test, or of its doc_test.go
 	Name          string
-	CommentHeader string
+	CommentHeader string // leading comment of the test file
 	TestUnits     []TestUnit
 }
 
 type TestUnit struct {
+	Kind            string // KindTest, KindBenchmark, KindFuzz or KindExample
 	CommentHeader   string
 	MachineTestName string
 	TestName        string
+	ClassName       string              // JUnit classname when it is not the package path, e.g. a Ginkgo suite description
+	ExampleOutput   string              // expected output of an Example function
+	File            string              // source file, slash-separated and relative to the scanned directory
+	Line            int                 // line the test is declared or started on
+	Tags            map[string][]string // "@key: value" comment tags by lower-cased key
+	Dynamic         bool                // only known from test results, its name was not predicted from source
+	Container       bool                // groups its subtests without a result of its own, e.g. a Ginkgo Describe
 	Subtests        []TestUnit
 }
 
+// stringList is a flag that may be repeated, collecting every value.
+type stringList []string
+
+func (l *stringList) String() string { return strings.Join(*l, ",") }
+
+func (l *stringList) Set(value string) error {
+	if value != "" {
+		*l = append(*l, value)
+	}
+	return nil
+}
+
 type ExpandedVar struct {
 	VarName  string
 	VarValue []string
@@ -45,18 +84,41 @@ type ExpandedVar struct {
 
 const MAX_GAP_SIZE = 10
 
+// MAX_LOOP_ITERATIONS caps how far a single loop is unrolled.
+const MAX_LOOP_ITERATIONS = 1000
+
 func main() {
 	flag.StringVar(&sourceDir, "source", ".", "source directory to scan for tests")
-	flag.StringVar(&outPath, "o", "TESTS.md", "output markdown file path")
-	flag.StringVar(&junitPath, "junit", "", "path to JUnit XML (required)")
+	flag.StringVar(&outPath, "o", "TESTS.md", "output file path")
+	flag.StringVar(&outFormat, "format", "markdown", "output format: markdown, json or html")
+	flag.Var(&junitPaths, "junit", "path or glob of JUnit XML files; may be repeated to merge shards")
+	flag.StringVar(&goTestJSON, "gotest-json", "", "path to `go test -json` output, or - for stdin (default: stdin when piped and -junit is not set)")
 	flag.IntVar(&failSnippetMax, "fail-snippet", 300, "max chars of failure message to include (0=hide)")
+	flag.StringVar(&linkBase, "link-base", "", "URL template for source links, e.g. https://github.com/owner/repo/blob/{sha}/{path}#L{line} (default: links relative to the output file)")
+	flag.StringVar(&linkSHA, "link-sha", os.Getenv("GITHUB_SHA"), "commit SHA substituted for {sha} in -link-base")
+	flag.IntVar(&slowestN, "slowest", 5, "number of slowest tests to list in the summary (0=hide)")
+	flag.StringVar(&githubOutput, "github-output", os.Getenv("GITHUB_OUTPUT"), "file to append the summary statistics to as GitHub Actions step outputs")
+	flag.BoolVar(&groupByPackage, "group-by-package", false, "group test suites under a heading per package with the package's doc summary")
+	flag.BoolVar(&docDetails, "details", false, "render each test's full doc comment as Markdown in a collapsible block")
+	flag.BoolVar(&nestedLayout, "nested", false, "list subtests in collapsible blocks under their parent test instead of flattening them into the suite table")
+	flag.BoolVar(&traceMode, "trace", false, "write a requirement → tests traceability matrix from @requirement tags instead of the test report")
+	flag.StringVar(&templatePath, "template", "", "text/template file to render the report with instead of -format (html/template for .html files), or \"default\" for the built-in Markdown template")
+	flag.StringVar(&reqPath, "requirements", "", "optional CSV or YAML requirement list for -trace, to report requirements without tests")
 	flag.Parse()
 
-	if junitPath == "" {
-		fmt.Fprintln(os.Stderr, "error: -junit path is required (provide JUnit XML from a previous step)")
+	if outFormat != "markdown" && outFormat != "json" && outFormat != "html" {
+		fmt.Fprintf(os.Stderr, "error: unknown -format %q (want markdown, json or html)\n", outFormat)
 		os.Exit(1)
 	}
 
+	if len(junitPaths) == 0 && goTestJSON == "" {
+		if !stdinIsPipe() {
+			fmt.Fprintln(os.Stderr, "error: -junit or -gotest-json is required (provide JUnit XML or go test -json output from a previous step)")
+			os.Exit(1)
+		}
+		goTestJSON = "-"
+	}
+
 	// 1) Gather static docs from source
 	testSuites, err := ParseTestSuites(sourceDir)
 	if err != nil {
@@ -89,25 +151,87 @@ func main() {
 		}
 	}
 
-	// 2) Read JUnit XML and attach statuses/durations/failures
-	jmap, err := ParseJUnitResults(junitPath)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "warn: reading junit: %v\n", err)
+	// 2) Read JUnit XML and/or go test -json and attach statuses/durations/failures
+	jmap := map[string]junitRecord{}
+	if len(junitPaths) > 0 {
+		results, err := ParseJUnitResults(junitPaths...)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "warn: reading junit: %v\n", err)
+		}
+		for key, rec := range results {
+			if len(rec.Conflicts) > 0 {
+				fmt.Fprintf(os.Stderr, "warn: conflicting results for %s: %s\n", key, strings.Join(rec.Conflicts, ", "))
+			}
+			jmap[key] = rec
+		}
+	}
+	if goTestJSON != "" {
+		results, err := ParseGoTestJSONFile(goTestJSON)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error reading go test -json: %v\n", err)
+			os.Exit(1)
+		}
+		for key, rec := range results {
+			jmap[key] = rec
+		}
+	}
+
+	// Results the source did not predict join the tree as dynamic tests
+	testSuites = ReconcileResults(testSuites, jmap)
+
+	opts := ReportOptions{
+		SourceDir: sourceDir,
+		LinkBase:  linkBase,
+		CommitSHA: linkSHA,
+
+		GroupByPackage: groupByPackage,
+		Details:        docDetails,
+		Nested:         nestedLayout,
+		FailSnippet:    failSnippetMax,
+		Slowest:        slowestN,
 	}
 
-	// 3) Generate markdown report
-	err = GenerateMarkdownReport(testSuites, jmap, outPath)
+	// 3) Generate markdown, JSON or HTML report, a templated one, or the
+	// traceability matrix
+	switch {
+	case traceMode:
+		var requirements []Requirement
+		if reqPath != "" {
+			requirements, err = ParseRequirements(reqPath)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "error reading requirements: %v\n", err)
+				os.Exit(1)
+			}
+		}
+		err = GenerateTraceabilityReport(testSuites, jmap, requirements, outPath, opts)
+	case templatePath != "":
+		err = GenerateTemplateReport(testSuites, jmap, outPath, templatePath, opts)
+	case outFormat == "json":
+		err = GenerateJSONReport(testSuites, jmap, outPath)
+	case outFormat == "html":
+		err = GenerateHTMLReport(testSuites, jmap, outPath, opts)
+	default:
+		err = GenerateMarkdownReport(testSuites, jmap, outPath, opts)
+	}
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "error generating markdown report: %v\n", err)
+		fmt.Fprintf(os.Stderr, "error generating report: %v\n", err)
 		os.Exit(1)
 	}
+
+	// 4) Expose the summary statistics to later workflow steps
+	if githubOutput != "" {
+		if err := WriteGitHubOutputs(githubOutput, ComputeStats(testSuites, jmap, slowestN)); err != nil {
+			fmt.Fprintf(os.Stderr, "error writing outputs: %v\n", err)
+			os.Exit(1)
+		}
+	}
 }
 
 /*** Package scanning (AST for summaries/tags/subtests) ***/
 func ParseTestSuites(sourceDir string) ([]TestSuite, error) {
 	cfg := &packages.Config{
 		Dir:        sourceDir,
-		Mode:       packages.NeedName | packages.NeedFiles | packages.NeedModule | packages.NeedForTest,
+		Mode:       packages.NeedName | packages.NeedFiles | packages.NeedModule | packages.NeedForTest | packages.NeedImports | packages.NeedTypes | packages.NeedSyntax | packages.NeedTypesInfo,
 		Tests:      true,
 		Env:        nil,
 		Fset:       nil,
@@ -119,7 +243,13 @@ func ParseTestSuites(sourceDir string) ([]TestSuite, error) {
 		return nil, err
 	}
 
+	root, err := filepath.Abs(sourceDir)
+	if err != nil {
+		return nil, err
+	}
+
 	var all []TestSuite
+	docs := make(packageDocs)
 
 	packages.Visit(pkgs, nil, func(p *packages.Package) {
 		if len(p.Errors) > 0 {
@@ -128,37 +258,50 @@ func ParseTestSuites(sourceDir string) ([]TestSuite, error) {
 			}
 		}
 
+		docs.record(p)
+
 		if strings.HasSuffix(p.Name, "_test") {
-			for _, filePath := range p.GoFiles {
-				fileSet := token.NewFileSet()
-				node, err := parser.ParseFile(fileSet, filePath, nil, parser.ParseComments)
-				if err != nil {
-					fmt.Fprintf(os.Stderr, "parse error %s: %v\n", filePath, err)
-					continue
-				}
+			pkg := newSourcePackage(p, root)
+			for _, node := range p.Syntax {
+				fileSet := p.Fset
+				filePath := fileSet.File(node.Pos()).Name()
 
 				// Create one test suite per file
 				var testUnits []TestUnit
 
+				examples := exampleOutputs(node)
+
 				ast.Inspect(node, func(n ast.Node) bool {
 					fd, ok := n.(*ast.FuncDecl)
 					if !ok || fd.Recv != nil || fd.Name == nil {
 						return true
 					}
 					name := fd.Name.Name
-					if !strings.HasPrefix(name, "Test") {
+					kind := TestFuncKind(name)
+					if kind == "" {
 						return true
 					}
 
 					file := fileSet.File(fd.End())
 
-					subs := CollectSubtests(fd.Body, node.Comments, file, filePath, name, nil)
+					var subs []TestUnit
+					switch kind {
+					case KindTest, KindBenchmark:
+						subs = CollectSubtests(pkg, fd.Body, node.Comments, file, filePath, name, nil)
+					case KindFuzz:
+						subs = CollectFuzzSeeds(pkg, fd.Body, node.Comments, file, filePath, name)
+					}
 
 					// Create a test unit for this function
+					unitFile, line := pkg.position(fd.Pos())
 					testUnits = append(testUnits, TestUnit{
+						Kind:            kind,
 						CommentHeader:   FindRelativeComment(fd.Pos(), node.Comments, file, filePath),
 						MachineTestName: name,
 						TestName:        name,
+						ExampleOutput:   examples[name],
+						File:            unitFile,
+						Line:            line,
 						Subtests:        subs,
 					})
 
@@ -167,10 +310,11 @@ func ParseTestSuites(sourceDir string) ([]TestSuite, error) {
 
 				// Only add suite if we found test functions
 				if len(testUnits) > 0 {
+					tagUnits(testUnits)
 					all = append(all, TestSuite{
 						PackageName:   strings.TrimSuffix(p.PkgPath, "_test"), // to match junit output
 						Name:          filepath.Base(filePath),
-						CommentHeader: "",
+						CommentHeader: fileDescription(node),
 						TestUnits:     testUnits,
 					})
 				}
@@ -178,116 +322,127 @@ func ParseTestSuites(sourceDir string) ([]TestSuite, error) {
 		}
 	})
 
+	for i := range all {
+		all[i].PackageDoc = docs[all[i].PackageName].text
+	}
+
 	return all, nil
 }
 
-func CollectSubtests(testBody *ast.BlockStmt, comments []*ast.CommentGroup, file *token.File, filePath string, parentName string, expandedVariables []ExpandedVar) []TestUnit {
+func CollectSubtests(pkg *sourcePackage, testBody *ast.BlockStmt, comments []*ast.CommentGroup, file *token.File, filePath string, parentName string, expandedVariables []ExpandedVar) []TestUnit {
 	tests := make([]TestUnit, 0)
-	var precedingComments string
-
-	ast.Inspect(testBody, func(n ast.Node) bool {
-		loop, ok := n.(*ast.RangeStmt)
-		if ok {
-			rangeValues := ExtractRangeValues(loop)
-
-			var loopVarName string
-			if loop.Value != nil {
-				if ident, ok := loop.Value.(*ast.Ident); ok {
-					loopVarName = ident.Name
-				}
-			}
-
-			expandedVariables = append(expandedVariables, ExpandedVar{VarName: loopVarName, VarValue: rangeValues})
-
-			ast.Inspect(loop.Body, func(innerN ast.Node) bool {
-				if call, ok := innerN.(*ast.CallExpr); ok {
-					if sel, ok := call.Fun.(*ast.SelectorExpr); ok && sel.Sel != nil && sel.Sel.Name == "Run" {
-						if len(call.Args) >= 2 {
-							precedingComments = FindRelativeComment(call.Pos(), comments, file, filePath)
-
-							// Expand test names for each loop value
-							expandedNames := ExpandTestName(call.Args[0], expandedVariables)
-							if testFunc, ok := call.Args[1].(*ast.FuncLit); ok {
-								// Create a test unit for each expanded name
-								for _, expandedName := range expandedNames {
-									subtests := CollectSubtests(testFunc.Body, comments, file, filePath, fmt.Sprintf("%s/%s", parentName, expandedName), expandedVariables)
-									tests = append(tests, TestUnit{
-										CommentHeader:   precedingComments,
-										MachineTestName: strings.ReplaceAll(fmt.Sprintf("%s/%s", parentName, expandedName), " ", "_"),
-										TestName:        expandedName,
-										Subtests:        subtests,
-									})
-								}
-							}
-						}
-						return false
-					}
-				}
-				return true
-			})
-
-			return false
-		}
 
-		call, ok := n.(*ast.CallExpr)
-		if !ok {
-			return true
-		}
-		sel, ok := call.Fun.(*ast.SelectorExpr)
-		if !ok || sel.Sel == nil || sel.Sel.Name != "Run" {
+	// visitCall records the subtests started by a t.Run call, or collected
+	// from a helper function in the package, and reports whether it did.
+	// Within a table-driven loop, the iteration's entry comment and position
+	// take the place of those of the call.
+	visitCall := func(call *ast.CallExpr, iteration loopIteration) bool {
+		vars := iteration.vars
+		// Ginkgo's RunSpecs(t, "Books Suite") runs the package's spec tree
+		if description, ok := pkg.ginkgoSuiteDescription(call); ok {
+			tests = append(tests, CollectGinkgoSpecs(pkg, description)...)
 			return true
 		}
 
-		if len(call.Args) == 0 {
+		// testify's suite.Run(t, new(MySuite)) runs the suite's Test* methods
+		if pkg.isSuiteRun(call) {
+			tests = append(tests, CollectSuiteTests(pkg, call, parentName, vars)...)
 			return true
 		}
 
-		precedingComments = FindRelativeComment(call.Pos(), comments, file, filePath)
+		sel, ok := call.Fun.(*ast.SelectorExpr)
+		if ok && sel.Sel != nil && sel.Sel.Name == "Run" {
+			if len(call.Args) < 2 {
+				return false
+			}
 
-		testNames := ExpandTestName(call.Args[0], expandedVariables)
+			precedingComments := FindRelativeComment(call.Pos(), comments, file, filePath)
+			if iteration.comment != "" {
+				precedingComments = iteration.comment
+			}
+			pos := call.Pos()
+			if iteration.pos.IsValid() {
+				pos = iteration.pos
+			}
+			unitFile, line := pkg.position(pos)
+
+			// The subtest body is a literal, a named function or a method value
+			testFunc, ok := call.Args[1].(*ast.FuncLit)
+			var testDecl *ast.FuncDecl
+			if !ok {
+				testDecl = pkg.funcDecl(call.Args[1])
+			}
 
-		if testFunc, ok := call.Args[1].(*ast.FuncLit); ok && len(testNames) > 0 {
-			for _, testName := range testNames {
-				unitName := fmt.Sprintf("%s/%s", parentName, testName)
-				subtests := CollectSubtests(testFunc.Body, comments, file, filePath, unitName, expandedVariables)
+			// Expand test names for each loop value
+			for _, testName := range expandTestName(pkg, call.Args[0], vars) {
+				machineName := pkg.subtestName(parentName, testName)
+				var subtests []TestUnit
+				switch {
+				case testFunc != nil:
+					subtests = CollectSubtests(pkg, testFunc.Body, comments, file, filePath, machineName, vars)
+				case testDecl != nil:
+					subtests = pkg.collectFromDecl(testDecl, nil, machineName, vars)
+				}
 				tests = append(tests, TestUnit{
 					CommentHeader:   precedingComments,
-					MachineTestName: strings.ReplaceAll(unitName, " ", "_"),
+					MachineTestName: machineName,
 					TestName:        testName,
+					File:            unitFile,
+					Line:            line,
 					Subtests:        subtests,
 				})
 			}
+			return true
 		}
 
+		// Helpers like runCases(t, cases) that call t.Run themselves
+		if helper := pkg.funcDecl(call.Fun); helper != nil {
+			tests = append(tests, pkg.collectFromDecl(helper, call.Args, parentName, vars)...)
+		}
 		return false
-	})
-	return tests
-}
-
-func ExtractRangeValues(rangeStmt *ast.RangeStmt) []string {
-	var values []string
+	}
 
-	if comp, ok := rangeStmt.X.(*ast.CompositeLit); ok {
-		for _, elt := range comp.Elts {
-			if lit, ok := elt.(*ast.BasicLit); ok && lit.Kind == token.STRING {
-				if s, err := strconv.Unquote(lit.Value); err == nil {
-					values = append(values, s)
+	// walk unrolls every loop it meets, so nested loops expand to the
+	// cartesian product of their iterations and sibling loops stay isolated.
+	var walk func(root ast.Node, outer loopIteration)
+	walk = func(root ast.Node, outer loopIteration) {
+		ast.Inspect(root, func(n ast.Node) bool {
+			switch n := n.(type) {
+			case *ast.RangeStmt:
+				for _, iteration := range rangeIterations(pkg, n, comments, file, filePath, outer.vars) {
+					walk(n.Body, iteration.within(outer))
 				}
-			} else {
-				// For non-string literals, convert to string representation
-				var sb strings.Builder
-				if err := format.Node(&sb, token.NewFileSet(), elt); err == nil {
-					values = append(values, sb.String())
+				return false
+
+			case *ast.ForStmt:
+				for _, iteration := range forIterations(pkg, n, outer.vars) {
+					walk(n.Body, iteration.within(outer))
 				}
+				return false
+
+			case *ast.CallExpr:
+				return !visitCall(n, outer)
 			}
-		}
+			return true
+		})
 	}
 
-	return values
+	walk(testBody, loopIteration{vars: expandedVariables})
+	return tests
 }
 
 // ExpandTestName substitutes the loop variable with a specific value
 func ExpandTestName(expr ast.Expr, expandedVariables []ExpandedVar) []string {
+	return expandTestName(nil, expr, expandedVariables)
+}
+
+// expandTestName is ExpandTestName with constant evaluation against the
+// package's type information, so named and typed constants resolve to values.
+func expandTestName(pkg *sourcePackage, expr ast.Expr, expandedVariables []ExpandedVar) []string {
+	if s, ok := pkg.constString(expr); ok {
+		return []string{s}
+	}
+
 	switch e := expr.(type) {
 	case *ast.BasicLit:
 		// Simple string literal - return as is
@@ -299,28 +454,40 @@ func ExpandTestName(expr ast.Expr, expandedVariables []ExpandedVar) []string {
 		return []string{e.Value}
 
 	case *ast.BinaryExpr:
-		// Handle string concatenation like "test"+input
-		if e.Op == token.ADD {
-			left := ExpandTestName(e.X, expandedVariables)
-			right := ExpandTestName(e.Y, expandedVariables)
-			// mix all combinations
-			var results []string
-			for _, l := range left {
-				for _, r := range right {
-					results = append(results, l+r)
-				}
-			}
-			return results
+		// String concatenation like "test"+input, or integer arithmetic
+		// like i+1
+		if values, ok := evalBinary(pkg, e, expandedVariables); ok {
+			return values
 		}
 
 	case *ast.Ident:
-		// Check if this ident matches any expanded variable
-		for _, ev := range expandedVariables {
-			if e.Name == ev.VarName {
+		// Check if this ident matches any expanded variable, innermost first
+		if ev, ok := lookupVar(expandedVariables, e.Name); ok {
+			return ev.VarValue
+		}
+		// Follow local variables like name := fmt.Sprintf(...)
+		if value := pkg.declaredValue(e); value != nil {
+			return expandTestName(pkg, value, expandedVariables)
+		}
+		return []string{e.Name}
+
+	case *ast.CallExpr:
+		// Type conversions like string(mode) keep the underlying value
+		if len(e.Args) == 1 && pkg.isConversion(e) {
+			return expandTestName(pkg, e.Args[0], expandedVariables)
+		}
+		// Pure formatting helpers like fmt.Sprintf("%s/%d", name, size)
+		if values, ok := evalCall(pkg, e, expandedVariables); ok {
+			return values
+		}
+
+	case *ast.SelectorExpr:
+		// Table-driven field access like tt.name
+		if x, ok := e.X.(*ast.Ident); ok {
+			if ev, ok := lookupVar(expandedVariables, x.Name+"."+e.Sel.Name); ok {
 				return ev.VarValue
 			}
 		}
-		return []string{e.Name}
 	}
 
 	// Fallback: convert entire expression to string
@@ -337,7 +504,7 @@ func FindRelativeComment(callPos token.Pos, comments []*ast.CommentGroup, file *
 	precedingComments := ""
 	for _, commentGroup := range comments {
 		// Check if comment is immediately before the statement (allowing for line endings)
-		if callPos-commentGroup.End() <= MAX_GAP_SIZE {
+		if commentGroup.End() <= callPos && callPos-commentGroup.End() <= MAX_GAP_SIZE {
 			funcOffset := file.Position(callPos).Offset
 			commentOffset := file.Position(commentGroup.End()).Offset
 
@@ -386,12 +553,15 @@ type junitSuite struct {
 	// Some generators put <properties>, <system-out>, etc. which we ignore here.
 }
 type junitCase struct {
-	XMLName xml.Name  `xml:"testcase"`
-	Class   string    `xml:"classname,attr"` // often "github.com/your/module/pkg"
-	Name    string    `xml:"name,attr"`      // "TestFoo[/Sub]"
-	Time    string    `xml:"time,attr"`      // seconds string
-	Failure *jFailure `xml:"failure"`
-	Skipped *jSkipped `xml:"skipped"`
+	XMLName   xml.Name  `xml:"testcase"`
+	Class     string    `xml:"classname,attr"` // often "github.com/your/module/pkg"
+	Name      string    `xml:"name,attr"`      // "TestFoo[/Sub]"
+	Time      string    `xml:"time,attr"`      // seconds string
+	Failure   *jFailure `xml:"failure"`
+	Error     *jFailure `xml:"error"` // panics and other errors outside assertions, e.g. from go-junit-report
+	Skipped   *jSkipped `xml:"skipped"`
+	SystemOut string    `xml:"system-out"`
+	SystemErr string    `xml:"system-err"`
 }
 type jFailure struct {
 	Message string `xml:"message,attr"`
@@ -402,12 +572,130 @@ type jSkipped struct {
 }
 
 type junitRecord struct {
-	Status   string // PASS/FAIL/SKIP
-	Duration string // like "0.13s"
-	Failure  string // message or body text
+	Status    string   // PASS/FAIL/ERROR/SKIP, or FLAKY when a failed test passed on a rerun
+	Duration  string   // like "0.13s"
+	Failure   string   // message or body text
+	Details   string   // body text of the failure or error, when it has a message too
+	Stdout    string   // captured <system-out>
+	Stderr    string   // captured <system-err>
+	Seconds   float64  // Duration in seconds, summed over attempts and the files the test appears in
+	Attempts  int      // times the test ran, more than once when failed tests are rerun
+	Conflicts []string // "<file>: <status>" of each file, when files disagree on the status
 }
 
-func ParseJUnitResults(path string) (map[string]junitRecord, error) {
+// addAttempt records a run of the test key, such as a rerun of a failed
+// test by gotestsum --rerun-fails. A test takes the status of its last
+// attempt, except that one passing after an earlier failure is FLAKY and
+// keeps that failure's message.
+func addAttempt(out map[string]junitRecord, key string, rec junitRecord) {
+	prev, ok := out[key]
+	if !ok {
+		rec.Attempts = 1
+		out[key] = rec
+		return
+	}
+
+	rec.Attempts = prev.Attempts + 1
+	rec.Seconds += prev.Seconds
+	rec.Duration = formatSeconds(rec.Seconds)
+	if rec.Status == "PASS" && isFailure(prev.Status) {
+		rec.Status = "FLAKY"
+	}
+	if rec.Failure == "" {
+		rec.Failure, rec.Details = prev.Failure, prev.Details
+	}
+	out[key] = rec
+}
+
+// ParseJUnitResults reads and merges the JUnit XML files matching each of
+// patterns, which may be file paths or globs like "shards/*.xml". A test
+// reported in several files, as when it is split across CI shards, gets the
+// sum of its durations and the most severe status (ERROR, then FAIL, then
+// FLAKY, then PASS, then SKIP); if the files disagree, the record lists each
+// file's status in Conflicts. A file matched by several patterns is read
+// once. Files that fail to parse are reported in the error, and the results
+// of the others are still returned.
+func ParseJUnitResults(patterns ...string) (map[string]junitRecord, error) {
+	var paths []string
+	var errs []error
+	seen := map[string]bool{}
+	for _, pattern := range patterns {
+		matches, err := filepath.Glob(pattern)
+		if err != nil || len(matches) == 0 {
+			// Not a glob, or one matching nothing: read it as a path so a
+			// missing file is reported
+			matches = []string{pattern}
+		}
+		for _, path := range matches {
+			if clean := filepath.Clean(path); !seen[clean] {
+				seen[clean] = true
+				paths = append(paths, path)
+			}
+		}
+	}
+
+	out := map[string]junitRecord{}
+	statuses := map[string][]string{}
+	for _, path := range paths {
+		records, err := parseJUnitFile(path)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", path, err))
+			continue
+		}
+		for key, rec := range records {
+			statuses[key] = append(statuses[key], fmt.Sprintf("%s: %s", path, rec.Status))
+			prev, ok := out[key]
+			if !ok {
+				out[key] = rec
+				continue
+			}
+			merged := rec
+			if statusSeverity(prev.Status) > statusSeverity(rec.Status) {
+				merged = prev
+			}
+			merged.Seconds = prev.Seconds + rec.Seconds
+			merged.Attempts = max(prev.Attempts, rec.Attempts) // shards are not reruns
+			merged.Duration = formatSeconds(merged.Seconds)
+			for _, status := range statuses[key][:len(statuses[key])-1] {
+				if !strings.HasSuffix(status, ": "+rec.Status) {
+					merged.Conflicts = append([]string(nil), statuses[key]...)
+					break
+				}
+			}
+			out[key] = merged
+		}
+	}
+	return out, errors.Join(errs...)
+}
+
+// isFailure reports whether a test with status did not pass.
+func isFailure(status string) bool {
+	return status == "FAIL" || status == "ERROR" || status == "FLAKY"
+}
+
+// statusSeverity orders statuses for merging: a failure anywhere wins.
+func statusSeverity(status string) int {
+	switch status {
+	case "ERROR":
+		return 4
+	case "FAIL":
+		return 3
+	case "FLAKY":
+		return 2
+	case "PASS":
+		return 1
+	default:
+		return 0
+	}
+}
+
+// formatSeconds formats a duration like JUnit times, rounded to the
+// microsecond so sums do not show floating point noise.
+func formatSeconds(seconds float64) string {
+	return strconv.FormatFloat(math.Round(seconds*1e6)/1e6, 'f', -1, 64) + "s"
+}
+
+func parseJUnitFile(path string) (map[string]junitRecord, error) {
 	b, err := os.ReadFile(path)
 	if err != nil {
 		return nil, err
@@ -447,30 +735,44 @@ func collectFromSuites(suites junitSuites) map[string]junitRecord {
 			test := tc.Name
 			status := "PASS"
 			failMsg := ""
+			details := ""
 			if tc.Skipped != nil {
 				status = "SKIP"
 				if tc.Skipped.Message != "" {
 					failMsg = tc.Skipped.Message
 				}
 			}
-			if tc.Failure != nil {
-				status = "FAIL"
-				if tc.Failure.Message != "" {
-					failMsg = tc.Failure.Message
-				} else if tc.Failure.Text != "" {
-					failMsg = tc.Failure.Text
+			for _, f := range []struct {
+				status  string
+				element *jFailure
+			}{{"FAIL", tc.Failure}, {"ERROR", tc.Error}} {
+				if f.element == nil {
+					continue
+				}
+				status = f.status
+				if f.element.Message != "" {
+					failMsg = f.element.Message
+					details = f.element.Text
+				} else if f.element.Text != "" {
+					failMsg = f.element.Text
 				}
 			}
 
 			duration := ""
+			seconds := 0.0
 			if strings.TrimSpace(tc.Time) != "" {
 				duration = fmt.Sprintf("%ss", strings.TrimSpace(tc.Time))
+				seconds, _ = strconv.ParseFloat(strings.TrimSpace(tc.Time), 64)
 			}
-			out[pkgKey(pkg, test)] = junitRecord{
+			addAttempt(out, pkgKey(pkg, test), junitRecord{
 				Status:   status,
 				Duration: duration,
 				Failure:  strings.TrimSpace(failMsg),
-			}
+				Details:  strings.TrimSpace(details),
+				Stdout:   strings.TrimSpace(tc.SystemOut),
+				Stderr:   strings.TrimSpace(tc.SystemErr),
+				Seconds:  seconds,
+			})
 		}
 	}
 	return out
@@ -478,97 +780,332 @@ func collectFromSuites(suites junitSuites) map[string]junitRecord {
 
 func pkgKey(pkg, test string) string { return strings.TrimSpace(pkg) + "::" + strings.TrimSpace(test) }
 
+// lookupRecord finds the JUnit record of a test in package pkgName. A
+// container has the most severe status of its subtests that have a result
+// and the sum of their durations.
+func lookupRecord(tu TestUnit, pkgName string, jmap map[string]junitRecord) (junitRecord, bool) {
+	if !tu.Container {
+		rec, ok := jmap[unitKey(tu, pkgName)]
+		return rec, ok
+	}
+
+	var rolled junitRecord
+	found := false
+	for _, sub := range tu.Subtests {
+		rec, ok := lookupRecord(sub, pkgName, jmap)
+		if !ok {
+			continue
+		}
+		if !found || statusSeverity(rec.Status) > statusSeverity(rolled.Status) {
+			rolled.Status = rec.Status
+		}
+		rolled.Seconds += rec.Seconds
+		found = true
+	}
+	if found {
+		rolled.Duration = formatSeconds(rolled.Seconds)
+	}
+	return rolled, found
+}
+
+// truncate shortens s to at most n runes, marking the cut with "…". When
+// the kept text has a line break in its second half, it is cut there so
+// output is not left mid-line.
 func truncate(s string, n int) string {
 	if n <= 0 || s == "" {
 		return ""
 	}
-	if len(s) <= n {
+	if utf8.RuneCountInString(s) <= n {
 		return s
 	}
-	return s[:n] + "…"
+	cut := s
+	for i := range s {
+		if n == 0 {
+			cut = s[:i]
+			break
+		}
+		n--
+	}
+	if i := strings.LastIndexByte(cut, '\n'); i > 0 && i >= len(cut)/2 {
+		cut = cut[:i]
+	}
+	return strings.TrimRight(cut, " \t\r\n") + "…"
 }
 
-func GenerateMarkdownReport(testSuites []TestSuite, jmap map[string]junitRecord, outPath string) error {
-	f, err := os.Create(outPath)
-	if err != nil {
-		return fmt.Errorf("error creating output file: %v", err)
+// ReportOptions controls how GenerateMarkdownReport renders the report.
+type ReportOptions struct {
+	// SourceDir is the directory the test suites were parsed from. When set,
+	// each test links to its source, relative to the report file.
+	SourceDir string
+	// LinkBase is a URL template for source links instead, such as
+	// "https://github.com/owner/repo/blob/{sha}/{path}#L{line}". {path} is
+	// relative to SourceDir; a template without it has "/{path}#L{line}"
+	// appended.
+	LinkBase string
+	// CommitSHA is substituted for {sha} in LinkBase.
+	CommitSHA string
+	// GroupByPackage nests the test suites of each package under a package
+	// heading showing the package's doc summary.
+	GroupByPackage bool
+	// Details renders each test's full doc comment as Markdown in a
+	// collapsible block after the suite table, and the suite description
+	// likewise. The table keeps the one-line summary.
+	Details bool
+	// FailSnippet is the most characters of failure details and captured
+	// output shown for each failed test; 0 hides them.
+	FailSnippet int
+	// Nested lists only the top-level tests in each suite table, each test
+	// with subtests followed by a collapsible block with a table of them,
+	// instead of a row per subtest.
+	Nested bool
+	// Slowest is the number of slowest tests the summary lists; 0 lists
+	// none.
+	Slowest int
+
+	reportDir string // absolute directory of the report file
+}
+
+// GenerateMarkdownReport writes the Markdown report, rendering the built-in
+// template, so that -template default is the same report.
+func GenerateMarkdownReport(testSuites []TestSuite, jmap map[string]junitRecord, outPath string, opts ReportOptions) error {
+	return GenerateTemplateReport(testSuites, jmap, outPath, defaultTemplateName, opts)
+}
+
+// flakyTest is a test of the Flaky Tests section, by its result key.
+type flakyTest struct {
+	Key      string
+	Attempts int
+	Failure  string
+}
+
+// flakyTests returns the tests that failed and then passed on a rerun.
+func flakyTests(jmap map[string]junitRecord) []flakyTest {
+	var tests []flakyTest
+	for key, rec := range jmap {
+		if rec.Status == "FLAKY" {
+			tests = append(tests, flakyTest{Key: key, Attempts: rec.Attempts, Failure: rec.Failure})
+		}
 	}
-	defer f.Close()
+	sort.Slice(tests, func(i, j int) bool { return tests[i].Key < tests[j].Key })
+	return tests
+}
 
-	w := func(format string, a ...interface{}) {
-		fmt.Fprintf(f, format, a...)
+// conflictingTest is a test whose merged JUnit files disagree on its status,
+// with the status in each file, like "shard-1.xml: PASS".
+type conflictingTest struct {
+	Key   string
+	Files []string
+}
+
+// conflictingTests returns the tests whose merged JUnit files disagree on
+// their status, such as a test passing in one shard and failing in another.
+func conflictingTests(jmap map[string]junitRecord) []conflictingTest {
+	var tests []conflictingTest
+	for key, rec := range jmap {
+		if len(rec.Conflicts) > 0 {
+			tests = append(tests, conflictingTest{Key: key, Files: rec.Conflicts})
+		}
 	}
+	sort.Slice(tests, func(i, j int) bool { return tests[i].Key < tests[j].Key })
+	return tests
+}
 
-	w("# Test Documentation Report\n\n")
+// unmatchedPackage is the number of dynamic tests of a package, whose names
+// were not predicted from source, pointing at naming gaps to fix.
+type unmatchedPackage struct {
+	Package string
+	Count   int
+}
 
-	for _, ts := range testSuites {
-		w("## Test Suite: %s\n\n", ts.Name)
+// unmatchedPackages counts the dynamic tests of each package with any.
+func unmatchedPackages(testSuites []TestSuite) []unmatchedPackage {
+	counts, pkgs := countDynamic(testSuites)
+	unmatched := make([]unmatchedPackage, 0, len(pkgs))
+	for _, pkg := range pkgs {
+		unmatched = append(unmatched, unmatchedPackage{Package: pkg, Count: counts[pkg]})
+	}
+	return unmatched
+}
 
-		if ts.CommentHeader != "" {
-			w("**Suite Description:**\n\n%s\n\n", ts.CommentHeader)
-		}
+// failedTest is a test of a Failures section: its failure message and
+// details, captured output, and the file:line locations they mention, each
+// text limited to the -fail-snippet length.
+type failedTest struct {
+	Path      string // like "TestParent → child" as in the Test Path column
+	Status    string
+	Failure   string
+	Stdout    string
+	Stderr    string
+	Locations []failureLocation
+}
+
+// failureLocation is a file:line reference in failure output, with the
+// link to it when the report links to sources.
+type failureLocation struct {
+	Ref string
+	URL string
+}
 
-		// Create table header
-		w("| Test Path | Status | Duration | Description | Failure |\n")
-		w("|-----------|--------|----------|-------------|----------|\n")
+// failedTests returns the tests and subtests that did not pass, depth
+// first, or none when opts.FailSnippet hides failure output.
+func failedTests(tests []ReportTest, opts ReportOptions) []failedTest {
+	if opts.FailSnippet <= 0 {
+		return nil
+	}
 
-		// Add main test and all subtests to the table
-		for _, tu := range ts.TestUnits {
-			generateTableRowsForTestUnit(w, tu, ts.PackageName, jmap, "")
+	var failed []failedTest
+	var visit func(test ReportTest, pathPrefix string)
+	visit = func(test ReportTest, pathPrefix string) {
+		currentPath := test.Name
+		if pathPrefix != "" {
+			currentPath = pathPrefix + " → " + test.Name
 		}
+		if isFailure(test.Status) && !test.Container {
+			failure := strings.TrimSpace(test.Failure + "\n" + test.Details)
+			f := failedTest{
+				Path:    currentPath,
+				Status:  test.Status,
+				Failure: truncate(failure, opts.FailSnippet),
+				Stdout:  truncate(test.Stdout, opts.FailSnippet),
+				Stderr:  truncate(test.Stderr, opts.FailSnippet),
+			}
+			for _, ref := range failureLocations(failure + "\n" + test.Stdout + "\n" + test.Stderr) {
+				f.Locations = append(f.Locations, failureLocation{Ref: ref, URL: locationURL(ref, test.File, opts)})
+			}
+			failed = append(failed, f)
+		}
+		for _, sub := range test.Subtests {
+			visit(sub, currentPath)
+		}
+	}
+	for _, test := range tests {
+		visit(test, "")
+	}
+	return failed
+}
 
-		w("\n")
+// goLocation matches file:line references like "parser_test.go:42" in Go
+// test output.
+var goLocation = regexp.MustCompile(`([\w./-]+\.go):(\d+)`)
+
+// failureLocations returns the distinct file:line references in text, in order.
+func failureLocations(text string) []string {
+	var refs []string
+	seen := make(map[string]bool)
+	for _, ref := range goLocation.FindAllString(text, -1) {
+		if !seen[ref] {
+			seen[ref] = true
+			refs = append(refs, ref)
+		}
 	}
+	return refs
+}
 
-	return nil
+// locationURL returns the link to a file:line reference from the failure
+// output of a test in testFile, or "" if there is none. go test prints file
+// names without their directory, so they are looked up in the directory of
+// the test's own file.
+func locationURL(ref, testFile string, opts ReportOptions) string {
+	m := goLocation.FindStringSubmatch(ref)
+	line, _ := strconv.Atoi(m[2])
+	if testFile == "" || strings.HasPrefix(m[1], "/") {
+		return ""
+	}
+	file := path.Join(path.Dir(testFile), path.Base(m[1]))
+	return sourceLink(TestUnit{File: file, Line: line}, opts)
 }
 
-func generateTableRowsForTestUnit(w func(string, ...interface{}), tu TestUnit, pkgName string, jmap map[string]junitRecord, pathPrefix string) {
-	// Build the current test path
-	currentPath := tu.TestName
-	if pathPrefix != "" {
-		currentPath = pathPrefix + " → " + tu.TestName
+// indent prefixes every non-empty line of s, nesting it in a list item.
+func indent(s, prefix string) string {
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		if line != "" {
+			lines[i] = prefix + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// fencedText puts text in a fenced code block, with a fence longer than any
+// run of backticks in the text.
+func fencedText(text string) string {
+	longest, run := 0, 0
+	for _, r := range text {
+		if r == '`' {
+			run++
+			longest = max(longest, run)
+		} else {
+			run = 0
+		}
 	}
+	fence := strings.Repeat("`", max(3, longest+1))
+	return fence + "text\n" + text + "\n" + fence
+}
 
-	// Lookup JUnit record
-	key := pkgKey(pkgName, tu.MachineTestName)
-	status := "NOT RUN"
-	duration := "-"
-	failure := ""
+// docDetail is the full doc comment of a test, by its path.
+type docDetail struct {
+	Path    string
+	Comment string
+}
 
-	if rec, ok := jmap[key]; ok {
-		status = rec.Status
-		if rec.Duration != "" {
-			duration = rec.Duration
+// detailedDocs returns the doc comments of tests and their subtests that
+// say more than their summary, depth first.
+func detailedDocs(tests []ReportTest) []docDetail {
+	var details []docDetail
+	var visit func(test ReportTest, pathPrefix string)
+	visit = func(test ReportTest, pathPrefix string) {
+		currentPath := test.Name
+		if pathPrefix != "" {
+			currentPath = pathPrefix + " → " + test.Name
+		}
+		if hasDetails(test.Comment) {
+			details = append(details, docDetail{Path: currentPath, Comment: test.Comment})
 		}
-		if rec.Status == "FAIL" && rec.Failure != "" {
-			failure = truncate(rec.Failure, 100) // Shorter for table
-			// Escape pipe characters that would break table
-			failure = strings.ReplaceAll(failure, "|", "\\|")
-			failure = strings.ReplaceAll(failure, "\n", " ")
+		for _, sub := range test.Subtests {
+			visit(sub, currentPath)
 		}
 	}
-
-	// Extract description from comments
-	description := ""
-	if tu.CommentHeader != "" {
-		description = extractSummaryFromComment(tu.CommentHeader)
-		// Escape pipe characters
-		description = strings.ReplaceAll(description, "|", "\\|")
-		description = strings.ReplaceAll(description, "\n", " ")
+	for _, test := range tests {
+		visit(test, "")
 	}
+	return details
+}
 
-	// Add status emoji
-	statusIcon := getStatusIcon(status)
+// sourceLink returns the link to the source of tu, or "" if it has no
+// position or the options ask for no links.
+func sourceLink(tu TestUnit, opts ReportOptions) string {
+	if tu.File == "" {
+		return ""
+	}
+	line := strconv.Itoa(tu.Line)
 
-	// Write the table row
-	w("| %s | %s %s | %s | %s | %s |\n",
-		currentPath, statusIcon, status, duration, description, failure)
+	if opts.LinkBase != "" {
+		link := strings.ReplaceAll(opts.LinkBase, "{sha}", opts.CommitSHA)
+		if !strings.Contains(link, "{path}") {
+			link = strings.TrimSuffix(link, "/") + "/{path}#L{line}"
+		}
+		link = strings.ReplaceAll(link, "{path}", (&url.URL{Path: tu.File}).EscapedPath())
+		return strings.ReplaceAll(link, "{line}", line)
+	}
 
-	// Recursively add subtests
-	for _, sub := range tu.Subtests {
-		generateTableRowsForTestUnit(w, sub, pkgName, jmap, currentPath)
+	if opts.SourceDir == "" || opts.reportDir == "" {
+		return ""
+	}
+	sourceDir, err := filepath.Abs(opts.SourceDir)
+	if err != nil {
+		return ""
+	}
+	rel, err := filepath.Rel(opts.reportDir, filepath.Join(sourceDir, filepath.FromSlash(tu.File)))
+	if err != nil {
+		return ""
 	}
+	return (&url.URL{Path: filepath.ToSlash(rel)}).EscapedPath() + "#L" + line
+}
+
+// escapeLinkText escapes the characters that would end Markdown link text
+// or break the table row.
+func escapeLinkText(s string) string {
+	return strings.NewReplacer("[", "\\[", "]", "\\]", "|", "\\|").Replace(s)
 }
 
 func getStatusIcon(status string) string {
@@ -579,6 +1116,10 @@ func getStatusIcon(status string) string {
 		return "❌"
 	case "SKIP":
 		return "⏭️"
+	case "FLAKY":
+		return "🔁"
+	case "ERROR":
+		return "💥"
 	default:
 		return "⚪"
 	}
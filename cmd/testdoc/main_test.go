@@ -1,17 +1,24 @@
 package main_test
 
 import (
+	"encoding/json"
+	"flag"
 	"go/ast"
 	"go/parser"
 	"go/token"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"strings"
 	"testing"
+	"unicode/utf8"
 
 	main "github.com/wleev/go-test-doc-action/cmd/testdoc"
 )
 
+// update rewrites the golden files under testdata with the current output
+var update = flag.Bool("update", false, "update golden files")
+
 // TestDocumentationGenerator tests the test documentation generation functionality
 // This test validates that the tool can parse its own test files and generate proper documentation
 func TestDocumentationGenerator(t *testing.T) {
@@ -237,7 +244,7 @@ go 1.21
 		}
 
 		// Generate markdown
-		err = main.GenerateMarkdownReport(testSuites, junitResults, outputFile)
+		err = main.GenerateMarkdownReport(testSuites, junitResults, outputFile, main.ReportOptions{})
 		if err != nil {
 			t.Fatalf("Failed to generate markdown: %v", err)
 		}
@@ -495,3 +502,2874 @@ func TestErrorHandling(t *testing.T) {
 		}
 	})
 }
+
+// TestTableDrivenExpansion tests expansion of table-driven tests declared as slices of structs
+// This validates that each table entry becomes its own subtest with its own comment
+func TestTableDrivenExpansion(t *testing.T) {
+	tempDir := writeSampleModule(t, map[string]string{"table_test.go": `package testproject_test
+
+import (
+	"fmt"
+	"testing"
+)
+
+type namedCase struct {
+	name  string
+	input int
+}
+
+var packageCases = []namedCase{
+	{"pkg_one", 1},
+	{"pkg_two", 2},
+}
+
+func TestKeyedTable(t *testing.T) {
+	tests := []struct {
+		name  string
+		input int
+	}{
+		// First entry checks the empty input
+		{name: "empty", input: 0},
+		// Second entry checks a positive input
+		{name: "positive", input: 1},
+		{name: "negative", input: -1},
+	}
+
+	for _, tt := range tests {
+		// Runs the table entry
+		t.Run(tt.name, func(t *testing.T) {
+			_ = tt.input
+		})
+	}
+}
+
+func TestPositionalTable(t *testing.T) {
+	for _, tc := range []*namedCase{
+		{"first case", 1},
+		&namedCase{"second case", 2},
+	} {
+		t.Run(tc.name, func(t *testing.T) {})
+	}
+}
+
+func TestPackageLevelTable(t *testing.T) {
+	for _, tc := range packageCases {
+		t.Run("case_"+tc.name, func(t *testing.T) {})
+	}
+}
+
+func TestOmittedFields(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   int
+		enabled bool
+	}{
+		{input: 1},
+		{input: 2, enabled: true},
+		{name: "named"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {})
+		t.Run(fmt.Sprintf("%d_%t", tt.input, tt.enabled), func(t *testing.T) {})
+	}
+}
+`})
+
+	testSuites, err := main.ParseTestSuites(tempDir)
+	if err != nil {
+		t.Fatalf("Failed to parse test suites: %v", err)
+	}
+	if len(testSuites) != 1 {
+		t.Fatalf("Expected 1 test suite, got %d", len(testSuites))
+	}
+
+	units := make(map[string]main.TestUnit)
+	for _, tu := range testSuites[0].TestUnits {
+		units[tu.TestName] = tu
+	}
+
+	t.Run("keyed_struct_literals", func(t *testing.T) {
+		subs := units["TestKeyedTable"].Subtests
+		expected := []string{"TestKeyedTable/empty", "TestKeyedTable/positive", "TestKeyedTable/negative"}
+		if len(subs) != len(expected) {
+			t.Fatalf("Expected %d subtests, got %d", len(expected), len(subs))
+		}
+		for i, name := range expected {
+			if subs[i].MachineTestName != name {
+				t.Errorf("Expected %s, got %s", name, subs[i].MachineTestName)
+			}
+		}
+	})
+
+	t.Run("entry_comments", func(t *testing.T) {
+		// Entry comments win over the comment above t.Run
+		subs := units["TestKeyedTable"].Subtests
+		if len(subs) != 3 {
+			t.Fatalf("Expected 3 subtests, got %d", len(subs))
+		}
+		if !strings.Contains(subs[0].CommentHeader, "empty input") {
+			t.Errorf("Expected entry comment for first subtest, got %q", subs[0].CommentHeader)
+		}
+		if !strings.Contains(subs[1].CommentHeader, "positive input") {
+			t.Errorf("Expected entry comment for second subtest, got %q", subs[1].CommentHeader)
+		}
+		if !strings.Contains(subs[2].CommentHeader, "Runs the table entry") {
+			t.Errorf("Expected t.Run comment for third subtest, got %q", subs[2].CommentHeader)
+		}
+	})
+
+	t.Run("positional_struct_literals", func(t *testing.T) {
+		subs := units["TestPositionalTable"].Subtests
+		expected := []string{"TestPositionalTable/first_case", "TestPositionalTable/second_case"}
+		if len(subs) != len(expected) {
+			t.Fatalf("Expected %d subtests, got %d", len(expected), len(subs))
+		}
+		for i, name := range expected {
+			if subs[i].MachineTestName != name {
+				t.Errorf("Expected %s, got %s", name, subs[i].MachineTestName)
+			}
+		}
+	})
+
+	t.Run("package_level_table", func(t *testing.T) {
+		subs := units["TestPackageLevelTable"].Subtests
+		expected := []string{"TestPackageLevelTable/case_pkg_one", "TestPackageLevelTable/case_pkg_two"}
+		if len(subs) != len(expected) {
+			t.Fatalf("Expected %d subtests, got %d", len(expected), len(subs))
+		}
+		for i, name := range expected {
+			if subs[i].MachineTestName != name {
+				t.Errorf("Expected %s, got %s", name, subs[i].MachineTestName)
+			}
+		}
+	})
+
+	t.Run("omitted_fields", func(t *testing.T) {
+		// Fields left out of keyed literals hold their zero value
+		subs := units["TestOmittedFields"].Subtests
+		expected := []string{
+			"TestOmittedFields/#00", "TestOmittedFields/1_false",
+			"TestOmittedFields/#01", "TestOmittedFields/2_true",
+			"TestOmittedFields/named", "TestOmittedFields/0_false",
+		}
+		if len(subs) != len(expected) {
+			t.Fatalf("Expected %d subtests, got %d", len(expected), len(subs))
+		}
+		for i, name := range expected {
+			if subs[i].MachineTestName != name {
+				t.Errorf("Expected %s, got %s", name, subs[i].MachineTestName)
+			}
+		}
+	})
+}
+
+// TestConstantNameResolution tests that constants used in subtest names resolve to their values
+// This validates the type-checked resolution of named, typed and grouped constants
+func TestConstantNameResolution(t *testing.T) {
+	tempDir := writeSampleModule(t, map[string]string{
+		"consts_test.go": `package testproject_test
+
+import "testing"
+
+type Mode string
+
+const (
+	FastMode Mode = "fast"
+	SlowMode Mode = "slow mode"
+)
+
+const prefix = "case_"
+
+func TestConstants(t *testing.T) {
+	t.Run(prefix+"plain", func(t *testing.T) {})
+	t.Run(string(FastMode), func(t *testing.T) {})
+
+	for _, mode := range []Mode{FastMode, SlowMode} {
+		t.Run(string(mode), func(t *testing.T) {})
+	}
+
+	for _, tc := range sharedCases {
+		t.Run(tc.name, func(t *testing.T) {})
+	}
+
+	kept := "kept"
+	t.Run(kept, func(t *testing.T) {})
+
+	reassigned := "first"
+	reassigned = "second"
+	t.Run(reassigned, func(t *testing.T) {})
+}
+`,
+		"cases_test.go": `package testproject_test
+
+const sharedName = "shared"
+
+var sharedCases = []struct {
+	name string
+}{
+	{sharedName + "_one"},
+	{name: prefix + "two"},
+}
+`,
+	})
+
+	testSuites, err := main.ParseTestSuites(tempDir)
+	if err != nil {
+		t.Fatalf("Failed to parse test suites: %v", err)
+	}
+
+	var constTest *main.TestUnit
+	for i := range testSuites {
+		for j := range testSuites[i].TestUnits {
+			if testSuites[i].TestUnits[j].TestName == "TestConstants" {
+				constTest = &testSuites[i].TestUnits[j]
+			}
+		}
+	}
+	if constTest == nil {
+		t.Fatal("TestConstants not found")
+	}
+
+	found := make(map[string]bool)
+	for _, sub := range constTest.Subtests {
+		found[sub.MachineTestName] = true
+	}
+
+	// Named constants, typed string constants and a table declared in another file
+	expected := []string{
+		"TestConstants/case_plain",
+		"TestConstants/fast",
+		"TestConstants/slow_mode",
+		"TestConstants/shared_one",
+		"TestConstants/case_two",
+		"TestConstants/kept",
+		// Reassigned variables fall back to their name rather than their initializer
+		"TestConstants/reassigned",
+	}
+	for _, name := range expected {
+		if !found[name] {
+			t.Errorf("Expected subtest %s not found in %v", name, found)
+		}
+	}
+	if found["TestConstants/first"] {
+		t.Error("Expected the initializer of a reassigned variable not to be used")
+	}
+}
+
+// TestFormattingCallEvaluation tests static evaluation of formatting calls in subtest names
+// This validates fmt.Sprintf, strconv and strings helpers combined with loop expansion
+func TestFormattingCallEvaluation(t *testing.T) {
+	tempDir := writeSampleModule(t, map[string]string{"format_test.go": `package testproject_test
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestFormatting(t *testing.T) {
+	for _, size := range []int{8, 16} {
+		t.Run(fmt.Sprintf("%s/%03d", "size", size), func(t *testing.T) {})
+	}
+
+	for _, n := range []int{1, 2} {
+		t.Run("itoa_"+strconv.Itoa(n), func(t *testing.T) {})
+	}
+
+	for _, word := range []string{"Mixed", "Case"} {
+		name := strings.ToLower(word) + "_" + strings.Repeat("x", 2)
+		t.Run(name, func(t *testing.T) {})
+	}
+
+	t.Run(strings.Join([]string{"a", "b", "c"}, "-"), func(t *testing.T) {})
+	t.Run(strconv.Quote("quoted"), func(t *testing.T) {})
+	t.Run(strconv.FormatInt(255, 16), func(t *testing.T) {})
+
+	for _, m := range []Mode{0, 1} {
+		t.Run(fmt.Sprintf("mode_%v", m), func(t *testing.T) {})
+	}
+	t.Run(fmt.Sprintf("level_%d", Level(2)), func(t *testing.T) {})
+
+	for i := 0; i < 3; i++ {
+		t.Run(fmt.Sprintf("case_%d", i+1), func(t *testing.T) {})
+	}
+	for i, name := range []string{"a", "b"} {
+		t.Run(strconv.Itoa(i*2-1)+"_"+name, func(t *testing.T) {})
+	}
+	for _, x := range []uint8{100, 200} {
+		t.Run(strconv.Itoa(int(x+100)), func(t *testing.T) {})
+	}
+}
+
+type Mode int
+
+func (m Mode) String() string { return [...]string{"fast", "slow"}[m] }
+
+type Level int
+`})
+
+	testSuites, err := main.ParseTestSuites(tempDir)
+	if err != nil {
+		t.Fatalf("Failed to parse test suites: %v", err)
+	}
+	if len(testSuites) != 1 || len(testSuites[0].TestUnits) != 1 {
+		t.Fatalf("Expected a single suite with TestFormatting")
+	}
+
+	found := make(map[string]bool)
+	for _, sub := range testSuites[0].TestUnits[0].Subtests {
+		found[sub.TestName] = true
+	}
+
+	expected := []string{
+		"size/008",
+		"size/016",
+		"itoa_1",
+		"itoa_2",
+		"mixed_xx",
+		"case_xx",
+		"a-b-c",
+		`"quoted"`,
+		"ff",
+		"level_2",
+		"case_1",
+		"case_2",
+		"case_3",
+		"-1_a",
+		"1_b",
+		"200",
+	}
+	for _, name := range expected {
+		if !found[name] {
+			t.Errorf("Expected subtest %s not found in %v", name, found)
+		}
+	}
+
+	// A fmt.Stringer prints its String() result, which is not known statically
+	for _, name := range []string{"mode_0", "mode_1"} {
+		if found[name] {
+			t.Errorf("Expected no subtest %s formatted from the underlying value of a Stringer", name)
+		}
+	}
+
+	// Integer arithmetic is evaluated, not concatenated, and overflow has
+	// no static value
+	for _, name := range []string{"case_11", "case_21", "01_a", "200100", "300"} {
+		if found[name] {
+			t.Errorf("Expected no subtest %s from concatenating integers", name)
+		}
+	}
+}
+
+// TestSubtestNameRewriting tests that generated subtest names match the names go test reports
+// This validates whitespace and non-printable rewriting and duplicate name suffixes
+func TestSubtestNameRewriting(t *testing.T) {
+	tempDir := writeSampleModule(t, map[string]string{"names_test.go": `package testproject_test
+
+import "testing"
+
+func TestNames(t *testing.T) {
+	t.Run("tab\there", func(t *testing.T) {})
+	t.Run("bell\a", func(t *testing.T) {})
+	t.Run("héllo wörld", func(t *testing.T) {})
+	t.Run("", func(t *testing.T) {})
+
+	for _, name := range []string{"dup", "dup", "dup#01", "dup"} {
+		t.Run(name, func(t *testing.T) {
+			t.Run("inner", func(t *testing.T) {})
+			t.Run("inner", func(t *testing.T) {})
+		})
+	}
+}
+`})
+
+	testSuites, err := main.ParseTestSuites(tempDir)
+	if err != nil {
+		t.Fatalf("Failed to parse test suites: %v", err)
+	}
+	if len(testSuites) != 1 {
+		t.Fatalf("Expected 1 test suite, got %d", len(testSuites))
+	}
+
+	var collect func(units []main.TestUnit, names map[string]bool)
+	collect = func(units []main.TestUnit, names map[string]bool) {
+		for _, tu := range units {
+			names[tu.MachineTestName] = true
+			collect(tu.Subtests, names)
+		}
+	}
+	generated := make(map[string]bool)
+	collect(testSuites[0].TestUnits, generated)
+
+	t.Run("expected_names", func(t *testing.T) {
+		expected := []string{
+			"TestNames/tab_here",
+			`TestNames/bell\a`,
+			"TestNames/héllo_wörld",
+			"TestNames/#00",
+			"TestNames/dup",
+			"TestNames/dup#01",
+			"TestNames/dup#01#01",
+			"TestNames/dup#02",
+			"TestNames/dup/inner",
+			"TestNames/dup/inner#01",
+			"TestNames/dup#02/inner#01",
+		}
+		for _, name := range expected {
+			if !generated[name] {
+				t.Errorf("Expected %q in generated names %v", name, generated)
+			}
+		}
+	})
+
+	t.Run("matches_go_test", func(t *testing.T) {
+		// Run the sample tests for real and compare against the names go test reports
+		cmd := exec.Command("go", "test", "-json", "./...")
+		cmd.Dir = tempDir
+		out, err := cmd.Output()
+		if err != nil {
+			t.Fatalf("go test failed: %v", err)
+		}
+
+		reported := make(map[string]bool)
+		for _, line := range strings.Split(string(out), "\n") {
+			var event struct {
+				Action string
+				Test   string
+			}
+			if json.Unmarshal([]byte(line), &event) == nil && event.Action == "run" {
+				reported[event.Test] = true
+			}
+		}
+		for name := range reported {
+			if !generated[name] {
+				t.Errorf("go test reported %q, which was not generated", name)
+			}
+		}
+		for name := range generated {
+			if !reported[name] {
+				t.Errorf("Generated %q, which go test did not report", name)
+			}
+		}
+	})
+}
+
+// TestBenchmarkFuzzExampleFunctions tests documentation of Benchmark, Fuzz and Example functions
+// This validates sub-benchmarks, fuzz seed corpus entries and example output blocks
+func TestBenchmarkFuzzExampleFunctions(t *testing.T) {
+	tempDir := writeSampleModule(t, map[string]string{"kinds_test.go": `package testproject_test
+
+import (
+	"fmt"
+	"testing"
+)
+
+// TestMain is setup, not a test
+func TestMain(m *testing.M) { m.Run() }
+
+// Testable is a helper, not a test
+func Testable() {}
+
+// BenchmarkSort measures sorting
+func BenchmarkSort(b *testing.B) {
+	for _, size := range []int{10, 100} {
+		b.Run(fmt.Sprintf("size_%d", size), func(b *testing.B) {})
+	}
+}
+
+// FuzzReverse fuzzes string reversal
+func FuzzReverse(f *testing.F) {
+	// Plain ASCII seed
+	f.Add("hello", 1)
+	for _, seed := range []string{"a", "b"} {
+		f.Add(seed, 2)
+	}
+	f.Fuzz(func(t *testing.T, s string, n int) {})
+}
+
+// ExampleGreeting shows the greeting
+func ExampleGreeting() {
+	fmt.Println("hello")
+	fmt.Println("world")
+	// Output:
+	// hello
+	// world
+}
+`})
+
+	testSuites, err := main.ParseTestSuites(tempDir)
+	if err != nil {
+		t.Fatalf("Failed to parse test suites: %v", err)
+	}
+	if len(testSuites) != 1 {
+		t.Fatalf("Expected 1 test suite, got %d", len(testSuites))
+	}
+
+	units := make(map[string]main.TestUnit)
+	for _, tu := range testSuites[0].TestUnits {
+		units[tu.TestName] = tu
+	}
+
+	t.Run("function_kinds", func(t *testing.T) {
+		if len(units) != 3 {
+			t.Errorf("Expected 3 test functions, got %d", len(units))
+		}
+		expected := map[string]string{
+			"BenchmarkSort":   main.KindBenchmark,
+			"FuzzReverse":     main.KindFuzz,
+			"ExampleGreeting": main.KindExample,
+		}
+		for name, kind := range expected {
+			if units[name].Kind != kind {
+				t.Errorf("Expected %s to be %s, got %q", name, kind, units[name].Kind)
+			}
+		}
+	})
+
+	t.Run("sub_benchmarks", func(t *testing.T) {
+		subs := units["BenchmarkSort"].Subtests
+		if len(subs) != 2 || subs[0].MachineTestName != "BenchmarkSort/size_10" || subs[1].MachineTestName != "BenchmarkSort/size_100" {
+			t.Errorf("Unexpected sub-benchmarks: %+v", subs)
+		}
+	})
+
+	t.Run("fuzz_seeds", func(t *testing.T) {
+		subs := units["FuzzReverse"].Subtests
+		expected := []string{`seed#0 ("hello", 1)`, `seed#1 ("a", 2)`, `seed#2 ("b", 2)`}
+		if len(subs) != len(expected) {
+			t.Fatalf("Expected %d seeds, got %d", len(expected), len(subs))
+		}
+		for i, name := range expected {
+			if subs[i].TestName != name {
+				t.Errorf("Expected %s, got %s", name, subs[i].TestName)
+			}
+		}
+		if subs[0].MachineTestName != "FuzzReverse/seed#0" {
+			t.Errorf("Expected FuzzReverse/seed#0, got %s", subs[0].MachineTestName)
+		}
+		if !strings.Contains(subs[0].CommentHeader, "Plain ASCII seed") {
+			t.Errorf("Expected seed comment, got %q", subs[0].CommentHeader)
+		}
+	})
+
+	t.Run("example_output", func(t *testing.T) {
+		if units["ExampleGreeting"].ExampleOutput != "hello\nworld\n" {
+			t.Errorf("Unexpected example output %q", units["ExampleGreeting"].ExampleOutput)
+		}
+	})
+
+	t.Run("markdown_sections", func(t *testing.T) {
+		outputFile := filepath.Join(t.TempDir(), "output.md")
+		if err := main.GenerateMarkdownReport(testSuites, nil, outputFile, main.ReportOptions{}); err != nil {
+			t.Fatalf("Failed to generate markdown: %v", err)
+		}
+		content, err := os.ReadFile(outputFile)
+		if err != nil {
+			t.Fatalf("Failed to read output file: %v", err)
+		}
+		output := string(content)
+		for _, section := range []string{"### Benchmarks", "### Fuzz Tests", "### Examples", "hello\nworld\n```"} {
+			if !strings.Contains(output, section) {
+				t.Errorf("Missing %q in output", section)
+			}
+		}
+	})
+}
+
+// TestHelperFunctionSubtests tests that subtests started in named functions and helpers are collected
+// This validates function references, method values, helpers with table parameters and recursion
+func TestHelperFunctionSubtests(t *testing.T) {
+	tempDir := writeSampleModule(t, map[string]string{
+		"helpers_test.go": `package testproject_test
+
+import "testing"
+
+type fixture struct{}
+
+func TestNamedFunctions(t *testing.T) {
+	// Runs the named test function
+	t.Run("by_reference", testByReference)
+
+	f := fixture{}
+	t.Run("by_method", f.testMethod)
+}
+
+func testByReference(t *testing.T) {
+	// Nested inside the referenced function
+	t.Run("nested", func(t *testing.T) {})
+}
+
+func (fixture) testMethod(t *testing.T) {
+	t.Run("method_nested", func(t *testing.T) {})
+}
+
+func TestHelperCalls(t *testing.T) {
+	runCases(t, helperCases)
+	recurse(t, 3)
+}
+
+func recurse(t *testing.T, depth int) {
+	t.Run("recursive", func(t *testing.T) {
+		recurse(t, depth-1)
+	})
+}
+`,
+		"cases_test.go": `package testproject_test
+
+import "testing"
+
+var helperCases = []struct {
+	name string
+}{
+	// First helper case
+	{name: "helper_one"},
+	{name: "helper_two"},
+}
+
+func runCases(t *testing.T, cases []struct{ name string }) {
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {})
+	}
+}
+`,
+		"runner_test.go": `package testproject_test
+
+import "testing"
+
+func TestTableInOtherFile(t *testing.T) {
+	for _, c := range helperCases {
+		t.Run(c.name, func(t *testing.T) {})
+	}
+}
+`,
+	})
+
+	testSuites, err := main.ParseTestSuites(tempDir)
+	if err != nil {
+		t.Fatalf("Failed to parse test suites: %v", err)
+	}
+
+	units := make(map[string]main.TestUnit)
+	for _, ts := range testSuites {
+		for _, tu := range ts.TestUnits {
+			units[tu.TestName] = tu
+		}
+	}
+
+	var collect func(units []main.TestUnit, names map[string]main.TestUnit)
+	collect = func(units []main.TestUnit, names map[string]main.TestUnit) {
+		for _, tu := range units {
+			names[tu.MachineTestName] = tu
+			collect(tu.Subtests, names)
+		}
+	}
+
+	t.Run("function_references", func(t *testing.T) {
+		names := make(map[string]main.TestUnit)
+		collect(units["TestNamedFunctions"].Subtests, names)
+		for _, name := range []string{
+			"TestNamedFunctions/by_reference",
+			"TestNamedFunctions/by_reference/nested",
+			"TestNamedFunctions/by_method",
+			"TestNamedFunctions/by_method/method_nested",
+		} {
+			if _, ok := names[name]; !ok {
+				t.Errorf("Expected subtest %s not found in %v", name, names)
+			}
+		}
+		if !strings.Contains(names["TestNamedFunctions/by_reference/nested"].CommentHeader, "Nested inside") {
+			t.Error("Expected comment from the referenced function body")
+		}
+	})
+
+	t.Run("helper_with_table_parameter", func(t *testing.T) {
+		names := make(map[string]main.TestUnit)
+		collect(units["TestHelperCalls"].Subtests, names)
+		for _, name := range []string{"TestHelperCalls/helper_one", "TestHelperCalls/helper_two"} {
+			if _, ok := names[name]; !ok {
+				t.Errorf("Expected subtest %s not found in %v", name, names)
+			}
+		}
+		if !strings.Contains(names["TestHelperCalls/helper_one"].CommentHeader, "First helper case") {
+			t.Error("Expected table entry comment in the helper's file")
+		}
+
+		other := units["TestTableInOtherFile"].Subtests
+		if len(other) != 2 || !strings.Contains(other[0].CommentHeader, "First helper case") {
+			t.Errorf("Expected table entry comment from the other file, got %+v", other)
+		}
+	})
+
+	t.Run("recursion_guard", func(t *testing.T) {
+		names := make(map[string]main.TestUnit)
+		collect(units["TestHelperCalls"].Subtests, names)
+		if _, ok := names["TestHelperCalls/recursive"]; !ok {
+			t.Error("Expected recursive helper subtest")
+		}
+		if _, ok := names["TestHelperCalls/recursive/recursive"]; ok {
+			t.Error("Recursive helper should only be collected once")
+		}
+	})
+}
+
+// TestTestifySuites tests documentation of testify suites run through suite.Run
+// This validates that suite methods and their s.Run subtests become children of the entry point
+func TestTestifySuites(t *testing.T) {
+	tempDir := writeSampleModule(t, map[string]string{
+		"go.mod": `module testproject
+
+go 1.21
+
+require github.com/stretchr/testify v1.0.0
+
+replace github.com/stretchr/testify => ./testify
+`,
+		// Minimal stand-in for testify so the sample builds offline
+		"testify/go.mod": "module github.com/stretchr/testify\n\ngo 1.21\n",
+		"testify/suite/suite.go": `package suite
+
+import "testing"
+
+type Suite struct{ t *testing.T }
+
+func (s *Suite) Run(name string, subtest func()) bool { return true }
+
+func Run(t *testing.T, suite interface{}) {}
+`,
+		"suite_test.go": `package testproject_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type AccountSuite struct {
+	suite.Suite
+}
+
+// TestAccountSuite runs the account suite
+func TestAccountSuite(t *testing.T) {
+	suite.Run(t, new(AccountSuite))
+}
+
+// TestWithdraw checks withdrawals
+func (s *AccountSuite) TestWithdraw() {
+	// Withdrawing more than the balance fails
+	s.Run("overdraft", func() {})
+	s.Run("exact balance", func() {})
+}
+
+func (s *AccountSuite) SetupTest() {}
+
+func (s *AccountSuite) helper() {}
+`,
+		"deposit_test.go": `package testproject_test
+
+// TestDeposit checks deposits
+func (s *AccountSuite) TestDeposit() {}
+`,
+	})
+
+	testSuites, err := main.ParseTestSuites(tempDir)
+	if err != nil {
+		t.Fatalf("Failed to parse test suites: %v", err)
+	}
+
+	var entry *main.TestUnit
+	for i := range testSuites {
+		for j := range testSuites[i].TestUnits {
+			if testSuites[i].TestUnits[j].TestName == "TestAccountSuite" {
+				entry = &testSuites[i].TestUnits[j]
+			}
+		}
+	}
+	if entry == nil {
+		t.Fatal("TestAccountSuite not found")
+	}
+
+	t.Run("suite_methods", func(t *testing.T) {
+		// Methods are ordered by name like testify runs them
+		expected := []string{"TestAccountSuite/TestDeposit", "TestAccountSuite/TestWithdraw"}
+		if len(entry.Subtests) != len(expected) {
+			t.Fatalf("Expected %d suite methods, got %+v", len(expected), entry.Subtests)
+		}
+		for i, name := range expected {
+			if entry.Subtests[i].MachineTestName != name {
+				t.Errorf("Expected %s, got %s", name, entry.Subtests[i].MachineTestName)
+			}
+		}
+		if !strings.Contains(entry.Subtests[0].CommentHeader, "checks deposits") {
+			t.Errorf("Expected method comment, got %q", entry.Subtests[0].CommentHeader)
+		}
+	})
+
+	t.Run("suite_subtests", func(t *testing.T) {
+		if len(entry.Subtests) != 2 {
+			t.Fatalf("Expected 2 suite methods, got %d", len(entry.Subtests))
+		}
+		subs := entry.Subtests[1].Subtests
+		expected := []string{"TestAccountSuite/TestWithdraw/overdraft", "TestAccountSuite/TestWithdraw/exact_balance"}
+		if len(subs) != len(expected) {
+			t.Fatalf("Expected %d s.Run subtests, got %+v", len(expected), subs)
+		}
+		for i, name := range expected {
+			if subs[i].MachineTestName != name {
+				t.Errorf("Expected %s, got %s", name, subs[i].MachineTestName)
+			}
+		}
+		if !strings.Contains(subs[0].CommentHeader, "more than the balance") {
+			t.Errorf("Expected s.Run comment, got %q", subs[0].CommentHeader)
+		}
+	})
+}
+
+// TestGinkgoSpecTree tests documentation of Ginkgo Describe/Context/It spec trees
+// This validates the nested hierarchy and matching against Ginkgo's JUnit reporter names
+func TestGinkgoSpecTree(t *testing.T) {
+	tempDir := writeSampleModule(t, map[string]string{
+		"go.mod": `module testproject
+
+go 1.21
+
+require github.com/onsi/ginkgo/v2 v2.0.0
+
+replace github.com/onsi/ginkgo/v2 => ./ginkgo
+`,
+		// Minimal stand-in for Ginkgo so the sample builds offline
+		"ginkgo/go.mod": "module github.com/onsi/ginkgo/v2\n\ngo 1.21\n",
+		"ginkgo/ginkgo.go": `package ginkgo
+
+import "testing"
+
+type Labels []string
+
+func RunSpecs(t *testing.T, description string, args ...interface{}) bool { return true }
+func Describe(text string, args ...interface{}) bool                    { return true }
+func Context(text string, args ...interface{}) bool                     { return true }
+func It(text string, args ...interface{}) bool                          { return true }
+func DescribeTable(text string, args ...interface{}) bool               { return true }
+func Entry(description interface{}, args ...interface{}) bool           { return true }
+func Label(labels ...string) Labels                                     { return labels }
+`,
+		"books_suite_test.go": `package books_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+)
+
+// TestBooks bootstraps the Ginkgo suite
+func TestBooks(t *testing.T) {
+	RunSpecs(t, "Books Suite")
+}
+`,
+		"books_test.go": `package books_test
+
+import . "github.com/onsi/ginkgo/v2"
+
+// Books covers book categorisation
+var _ = Describe("Books", func() {
+	Context("with more than 300 pages", func() {
+		// Long books are novels
+		It("should be a novel", func() {})
+	})
+
+	It("can be labelled", Label("slow"), func() {})
+
+	DescribeTable("page counts",
+		func(pages int) {},
+		Entry("short", 10),
+		Entry("long", 500),
+	)
+})
+`,
+		"junit.xml": `<?xml version="1.0" encoding="UTF-8"?>
+<testsuites tests="4" failures="1">
+	<testsuite name="Books Suite" package="/tmp/books" tests="4" failures="1">
+		<testcase name="[It] Books with more than 300 pages should be a novel" classname="Books Suite" status="passed" time="0.001"></testcase>
+		<testcase name="[It] Books can be labelled [slow]" classname="Books Suite" status="passed" time="0.002"></testcase>
+		<testcase name="[It] Books page counts short" classname="Books Suite" status="passed" time="0.001"></testcase>
+		<testcase name="[It] Books page counts long" classname="Books Suite" status="failed" time="0.001">
+			<failure message="Expected 500 to be less than 400" type="failed"></failure>
+		</testcase>
+	</testsuite>
+</testsuites>`,
+	})
+
+	testSuites, err := main.ParseTestSuites(tempDir)
+	if err != nil {
+		t.Fatalf("Failed to parse test suites: %v", err)
+	}
+
+	var bootstrap *main.TestUnit
+	for i := range testSuites {
+		for j := range testSuites[i].TestUnits {
+			if testSuites[i].TestUnits[j].TestName == "TestBooks" {
+				bootstrap = &testSuites[i].TestUnits[j]
+			}
+		}
+	}
+	if bootstrap == nil {
+		t.Fatal("TestBooks not found")
+	}
+
+	t.Run("spec_hierarchy", func(t *testing.T) {
+		if len(bootstrap.Subtests) != 1 || bootstrap.Subtests[0].TestName != "Books" {
+			t.Fatalf("Expected the Books container under TestBooks, got %+v", bootstrap.Subtests)
+		}
+		books := bootstrap.Subtests[0]
+		if !strings.Contains(books.CommentHeader, "book categorisation") {
+			t.Errorf("Expected container comment, got %q", books.CommentHeader)
+		}
+		if len(books.Subtests) != 3 {
+			t.Fatalf("Expected 3 children of Books, got %+v", books.Subtests)
+		}
+		novel := books.Subtests[0].Subtests
+		if len(novel) != 1 || novel[0].MachineTestName != "[It] Books with more than 300 pages should be a novel" {
+			t.Errorf("Unexpected nested spec %+v", novel)
+		}
+		if len(novel) == 1 && !strings.Contains(novel[0].CommentHeader, "Long books are novels") {
+			t.Errorf("Expected spec comment, got %q", novel[0].CommentHeader)
+		}
+		if books.Subtests[1].MachineTestName != "[It] Books can be labelled [slow]" {
+			t.Errorf("Expected labelled spec name, got %s", books.Subtests[1].MachineTestName)
+		}
+		entries := books.Subtests[2].Subtests
+		if len(entries) != 2 || entries[1].MachineTestName != "[It] Books page counts long" {
+			t.Errorf("Unexpected table entries %+v", entries)
+		}
+	})
+
+	t.Run("junit_matching", func(t *testing.T) {
+		junitResults, err := main.ParseJUnitResults(filepath.Join(tempDir, "junit.xml"))
+		if err != nil {
+			t.Fatalf("Failed to parse JUnit results: %v", err)
+		}
+		outputFile := filepath.Join(t.TempDir(), "output.md")
+		if err := main.GenerateMarkdownReport(testSuites, junitResults, outputFile, main.ReportOptions{}); err != nil {
+			t.Fatalf("Failed to generate markdown: %v", err)
+		}
+		content, err := os.ReadFile(outputFile)
+		if err != nil {
+			t.Fatalf("Failed to read output file: %v", err)
+		}
+		output := string(content)
+		for _, row := range []string{
+			"| TestBooks → Books → with more than 300 pages → should be a novel | ✅ PASS |",
+			"| TestBooks → Books → can be labelled | ✅ PASS |",
+			"| TestBooks → Books → page counts → long | ❌ FAIL |",
+			"| TestBooks → Books | ❌ FAIL | 0.005s |",
+			"| TestBooks → Books → with more than 300 pages | ✅ PASS | 0.001s |",
+		} {
+			if !strings.Contains(output, row) {
+				t.Errorf("Missing row %q in output:\n%s", row, output)
+			}
+		}
+	})
+
+	t.Run("container_rollup", func(t *testing.T) {
+		junitResults, err := main.ParseJUnitResults(filepath.Join(tempDir, "junit.xml"))
+		if err != nil {
+			t.Fatalf("Failed to parse JUnit results: %v", err)
+		}
+		if books := bootstrap.Subtests[0]; !books.Container || books.Subtests[1].Container {
+			t.Errorf("Expected only container nodes to be marked, got %+v", books)
+		}
+		// TestBooks and the four specs count; their containers do not
+		stats := main.ComputeStats(testSuites, junitResults, 0)
+		if stats.Total != 5 || stats.Passed != 3 || stats.Failed != 1 || stats.NotRun != 1 || stats.Undocumented != 3 {
+			t.Errorf("Expected containers to be left out of the counts, got %+v", stats)
+		}
+	})
+}
+
+// TestNestedLoopExpansion tests that nested loops expand to the cartesian product of their iterations
+// This validates key/value bindings, map literals, integer ranges, counting loops and sibling loop scoping
+func TestNestedLoopExpansion(t *testing.T) {
+	tempDir := writeSampleModule(t, map[string]string{
+		"go.mod": "module testproject\n\ngo 1.22\n",
+		"loops_test.go": `package testproject_test
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestCartesian(t *testing.T) {
+	for _, db := range []string{"pg", "mysql"} {
+		for _, size := range []int{1, 10} {
+			t.Run(fmt.Sprintf("%s/%d", db, size), func(t *testing.T) {
+				for _, mode := range []string{"read", "write"} {
+					t.Run(mode, func(t *testing.T) {})
+				}
+			})
+		}
+	}
+}
+
+func TestKeyValue(t *testing.T) {
+	cases := map[string]struct{ want int }{
+		// Empty input
+		"empty": {0},
+		"one":   {1},
+	}
+	for name, tc := range cases {
+		t.Run(fmt.Sprintf("%s=%d", name, tc.want), func(t *testing.T) {})
+	}
+
+	for i, v := range []string{"a", "b"} {
+		t.Run(fmt.Sprintf("%d_%s", i, v), func(t *testing.T) {})
+	}
+}
+
+func TestCounting(t *testing.T) {
+	for i := range 3 {
+		t.Run(fmt.Sprintf("range_%d", i), func(t *testing.T) {})
+	}
+	for i := 10; i > 0; i -= 4 {
+		t.Run(fmt.Sprintf("step_%d", i), func(t *testing.T) {})
+	}
+}
+
+func TestSiblingLoops(t *testing.T) {
+	for _, v := range []string{"x", "y"} {
+		t.Run(v, func(t *testing.T) {})
+	}
+	for _, v := range []string{"z"} {
+		t.Run("second_"+v, func(t *testing.T) {})
+	}
+}
+`,
+	})
+
+	testSuites, err := main.ParseTestSuites(tempDir)
+	if err != nil {
+		t.Fatalf("Failed to parse test suites: %v", err)
+	}
+
+	units := make(map[string]main.TestUnit)
+	for _, ts := range testSuites {
+		for _, tu := range ts.TestUnits {
+			units[tu.TestName] = tu
+		}
+	}
+
+	var flatten func(units []main.TestUnit) []string
+	flatten = func(units []main.TestUnit) []string {
+		var names []string
+		for _, tu := range units {
+			names = append(names, tu.MachineTestName)
+			names = append(names, flatten(tu.Subtests)...)
+		}
+		return names
+	}
+
+	expect := func(t *testing.T, test string, want []string) {
+		t.Helper()
+		got := flatten(units[test].Subtests)
+		if strings.Join(got, "\n") != strings.Join(want, "\n") {
+			t.Errorf("Expected subtests %v, got %v", want, got)
+		}
+	}
+
+	t.Run("multi_level_cartesian", func(t *testing.T) {
+		expect(t, "TestCartesian", []string{
+			"TestCartesian/pg/1", "TestCartesian/pg/1/read", "TestCartesian/pg/1/write",
+			"TestCartesian/pg/10", "TestCartesian/pg/10/read", "TestCartesian/pg/10/write",
+			"TestCartesian/mysql/1", "TestCartesian/mysql/1/read", "TestCartesian/mysql/1/write",
+			"TestCartesian/mysql/10", "TestCartesian/mysql/10/read", "TestCartesian/mysql/10/write",
+		})
+	})
+
+	t.Run("keys_and_values", func(t *testing.T) {
+		expect(t, "TestKeyValue", []string{
+			"TestKeyValue/empty=0", "TestKeyValue/one=1",
+			"TestKeyValue/0_a", "TestKeyValue/1_b",
+		})
+		if !strings.Contains(units["TestKeyValue"].Subtests[0].CommentHeader, "Empty input") {
+			t.Error("Expected map entry comment on the subtest")
+		}
+	})
+
+	t.Run("integer_ranges", func(t *testing.T) {
+		expect(t, "TestCounting", []string{
+			"TestCounting/range_0", "TestCounting/range_1", "TestCounting/range_2",
+			"TestCounting/step_10", "TestCounting/step_6", "TestCounting/step_2",
+		})
+	})
+
+	t.Run("sibling_loops_isolated", func(t *testing.T) {
+		expect(t, "TestSiblingLoops", []string{
+			"TestSiblingLoops/x", "TestSiblingLoops/y", "TestSiblingLoops/second_z",
+		})
+	})
+}
+
+// TestSourceLocations tests that every test and subtest records where it is declared
+// This validates file and line positions, table entry positions and source links in the report
+func TestSourceLocations(t *testing.T) {
+	tempDir := writeSampleModule(t, map[string]string{
+		"pkg/locations_test.go": `package pkg_test
+
+import "testing"
+
+func TestLocated(t *testing.T) {
+	t.Run("inline", func(t *testing.T) {})
+
+	tests := []struct{ name string }{
+		{name: "first"},
+		{name: "second"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {})
+	}
+}
+`,
+	})
+
+	testSuites, err := main.ParseTestSuites(tempDir)
+	if err != nil {
+		t.Fatalf("Failed to parse test suites: %v", err)
+	}
+	if len(testSuites) != 1 || len(testSuites[0].TestUnits) != 1 {
+		t.Fatalf("Expected a single test, got %+v", testSuites)
+	}
+	located := testSuites[0].TestUnits[0]
+
+	t.Run("positions", func(t *testing.T) {
+		if located.File != "pkg/locations_test.go" || located.Line != 5 {
+			t.Errorf("Expected pkg/locations_test.go:5, got %s:%d", located.File, located.Line)
+		}
+		want := map[string]int{"inline": 6, "first": 9, "second": 10}
+		for _, sub := range located.Subtests {
+			if sub.File != located.File || sub.Line != want[sub.TestName] {
+				t.Errorf("Expected %s at line %d, got %s:%d", sub.TestName, want[sub.TestName], sub.File, sub.Line)
+			}
+		}
+	})
+
+	generate := func(t *testing.T, outputFile string, opts main.ReportOptions) string {
+		t.Helper()
+		if err := main.GenerateMarkdownReport(testSuites, nil, outputFile, opts); err != nil {
+			t.Fatalf("Failed to generate markdown: %v", err)
+		}
+		content, err := os.ReadFile(outputFile)
+		if err != nil {
+			t.Fatalf("Failed to read output file: %v", err)
+		}
+		return string(content)
+	}
+
+	t.Run("relative_links", func(t *testing.T) {
+		docsDir := filepath.Join(tempDir, "docs")
+		if err := os.MkdirAll(docsDir, 0755); err != nil {
+			t.Fatalf("Failed to create docs directory: %v", err)
+		}
+		output := generate(t, filepath.Join(docsDir, "TESTS.md"), main.ReportOptions{SourceDir: tempDir})
+		for _, row := range []string{
+			"| [TestLocated](../pkg/locations_test.go#L5) |",
+			"| TestLocated → [first](../pkg/locations_test.go#L9) |",
+		} {
+			if !strings.Contains(output, row) {
+				t.Errorf("Missing row %q in output:\n%s", row, output)
+			}
+		}
+	})
+
+	t.Run("link_base", func(t *testing.T) {
+		output := generate(t, filepath.Join(t.TempDir(), "TESTS.md"), main.ReportOptions{
+			SourceDir: tempDir,
+			LinkBase:  "https://github.com/owner/repo/blob/{sha}/",
+			CommitSHA: "abc123",
+		})
+		row := "| TestLocated → [inline](https://github.com/owner/repo/blob/abc123/pkg/locations_test.go#L6) |"
+		if !strings.Contains(output, row) {
+			t.Errorf("Missing row %q in output:\n%s", row, output)
+		}
+	})
+
+	t.Run("no_links_without_options", func(t *testing.T) {
+		output := generate(t, filepath.Join(t.TempDir(), "TESTS.md"), main.ReportOptions{})
+		if strings.Contains(output, "](") {
+			t.Errorf("Expected no links in output:\n%s", output)
+		}
+	})
+}
+
+// TestCommentTags tests parsing of structured @key: value tags in test comments
+// This validates known and unknown tags, repeated keys and tag badges in the report
+func TestCommentTags(t *testing.T) {
+	t.Run("parse_tags", func(t *testing.T) {
+		tags := main.ParseCommentTags("Checks the refund flow\n@owner: payments-team\n@Requirement: REQ-101\n@requirement: REQ-102\n@flaky\n@custom-key: some value\nnot @a tag\n@requirement:REQ-103\n@issue:#42\n")
+		expected := map[string][]string{
+			"owner":       {"payments-team"},
+			"requirement": {"REQ-101", "REQ-102", "REQ-103"},
+			"issue":       {"#42"},
+			"flaky":       {""},
+			"custom-key":  {"some value"},
+		}
+		if len(tags) != len(expected) {
+			t.Errorf("Expected %d tags, got %v", len(expected), tags)
+		}
+		for key, values := range expected {
+			if strings.Join(tags[key], ",") != strings.Join(values, ",") {
+				t.Errorf("Expected %s=%v, got %v", key, values, tags[key])
+			}
+		}
+		if main.ParseCommentTags("No tags here") != nil {
+			t.Error("Expected nil tags for an untagged comment")
+		}
+	})
+
+	tempDir := writeSampleModule(t, map[string]string{
+		"tags_test.go": `package testproject_test
+
+import "testing"
+
+// TestRefunds checks the refund flow
+// @owner: payments-team
+// @severity: high
+// @internal: not rendered
+func TestRefunds(t *testing.T) {
+	// Partial refunds
+	// @requirement: REQ-7
+	t.Run("partial", func(t *testing.T) {})
+}
+`,
+	})
+
+	testSuites, err := main.ParseTestSuites(tempDir)
+	if err != nil {
+		t.Fatalf("Failed to parse test suites: %v", err)
+	}
+
+	t.Run("tags_on_units", func(t *testing.T) {
+		refunds := testSuites[0].TestUnits[0]
+		if refunds.Tags["owner"][0] != "payments-team" || refunds.Tags["internal"][0] != "not rendered" {
+			t.Errorf("Unexpected tags %v", refunds.Tags)
+		}
+		if refunds.Subtests[0].Tags["requirement"][0] != "REQ-7" {
+			t.Errorf("Unexpected subtest tags %v", refunds.Subtests[0].Tags)
+		}
+	})
+
+	t.Run("badges_in_report", func(t *testing.T) {
+		outputFile := filepath.Join(t.TempDir(), "output.md")
+		if err := main.GenerateMarkdownReport(testSuites, nil, outputFile, main.ReportOptions{}); err != nil {
+			t.Fatalf("Failed to generate markdown: %v", err)
+		}
+		content, err := os.ReadFile(outputFile)
+		if err != nil {
+			t.Fatalf("Failed to read output file: %v", err)
+		}
+		output := string(content)
+		for _, row := range []string{
+			"| TestRefunds checks the refund flow `owner: payments-team` `severity: high` |",
+			"| Partial refunds `requirement: REQ-7` |",
+		} {
+			if !strings.Contains(output, row) {
+				t.Errorf("Missing %q in output:\n%s", row, output)
+			}
+		}
+		if strings.Contains(output, "not rendered") {
+			t.Error("Unknown tags should not be rendered")
+		}
+	})
+}
+
+// TestRequirementsTraceability tests the requirement to tests matrix built from @requirement tags
+// This validates status aggregation, unverified requirements and external CSV and YAML requirement lists
+func TestRequirementsTraceability(t *testing.T) {
+	tempDir := writeSampleModule(t, map[string]string{
+		"trace_test.go": `package testproject_test
+
+import "testing"
+
+// TestLogin checks password login
+// @requirement: REQ-1, REQ-2
+func TestLogin(t *testing.T) {
+	// Locks the account after repeated failures
+	// @requirement: REQ-3
+	t.Run("lockout", func(t *testing.T) {})
+}
+
+// TestLogout checks sessions end on logout
+// @requirement: REQ-2
+func TestLogout(t *testing.T) {}
+
+// TestAudit checks login attempts are audited
+// @requirement: REQ-4
+func TestAudit(t *testing.T) {}
+`,
+		"junit.xml": `<?xml version="1.0" encoding="UTF-8"?>
+<testsuites>
+  <testsuite name="testproject" tests="3">
+    <testcase classname="testproject" name="TestLogin" time="0.01"></testcase>
+    <testcase classname="testproject" name="TestLogin/lockout" time="0.01">
+      <failure message="account not locked"></failure>
+    </testcase>
+    <testcase classname="testproject" name="TestLogout" time="0.01">
+      <failure message="session still active"></failure>
+    </testcase>
+  </testsuite>
+</testsuites>
+`,
+		"requirements.csv":  "id,description\nREQ-1,Users log in with a password\nREQ-3,Accounts lock after failures\nREQ-9,Passwords expire\n",
+		"requirements.yaml": "- id: REQ-1\n  description: Users log in with a password\n- REQ-9\n",
+	})
+
+	testSuites, err := main.ParseTestSuites(tempDir)
+	if err != nil {
+		t.Fatalf("Failed to parse test suites: %v", err)
+	}
+	junitResults, err := main.ParseJUnitResults(filepath.Join(tempDir, "junit.xml"))
+	if err != nil {
+		t.Fatalf("Failed to parse JUnit results: %v", err)
+	}
+
+	t.Run("aggregated_status", func(t *testing.T) {
+		traces := main.BuildTraceability(testSuites, junitResults, nil)
+		statuses := make(map[string]string)
+		var ids []string
+		for _, trace := range traces {
+			statuses[trace.ID] = trace.Status
+			ids = append(ids, trace.ID)
+		}
+		if strings.Join(ids, ",") != "REQ-1,REQ-2,REQ-3,REQ-4" {
+			t.Errorf("Unexpected requirement order %v", ids)
+		}
+		expected := map[string]string{"REQ-1": "PASS", "REQ-2": "PASS", "REQ-3": "FAIL", "REQ-4": "NOT RUN"}
+		for id, status := range expected {
+			if statuses[id] != status {
+				t.Errorf("Expected %s to be %s, got %s", id, status, statuses[id])
+			}
+		}
+	})
+
+	t.Run("requirement_lists", func(t *testing.T) {
+		for _, file := range []string{"requirements.csv", "requirements.yaml"} {
+			requirements, err := main.ParseRequirements(filepath.Join(tempDir, file))
+			if err != nil {
+				t.Fatalf("Failed to parse %s: %v", file, err)
+			}
+			if len(requirements) < 2 || requirements[0].ID != "REQ-1" || requirements[0].Description != "Users log in with a password" {
+				t.Errorf("Unexpected requirements from %s: %+v", file, requirements)
+			}
+		}
+		if _, err := main.ParseRequirements(filepath.Join(tempDir, "go.mod")); err == nil {
+			t.Error("Expected error for an unsupported requirement list")
+		}
+	})
+
+	t.Run("traceability_report", func(t *testing.T) {
+		requirements, err := main.ParseRequirements(filepath.Join(tempDir, "requirements.csv"))
+		if err != nil {
+			t.Fatalf("Failed to parse requirements: %v", err)
+		}
+		outputFile := filepath.Join(t.TempDir(), "TRACE.md")
+		if err := main.GenerateTraceabilityReport(testSuites, junitResults, requirements, outputFile, main.ReportOptions{}); err != nil {
+			t.Fatalf("Failed to generate traceability report: %v", err)
+		}
+		content, err := os.ReadFile(outputFile)
+		if err != nil {
+			t.Fatalf("Failed to read output file: %v", err)
+		}
+		output := string(content)
+		for _, expected := range []string{
+			"| REQ-1 | ✅ PASS | Users log in with a password | ✅ TestLogin |",
+			"| REQ-3 | ❌ FAIL | Accounts lock after failures | ❌ TestLogin → lockout |",
+			"| REQ-2 | ✅ PASS |  | ✅ TestLogin<br>❌ TestLogout |",
+			"## ⚠️ Unverified Requirements\n\nNo test covering these requirements passed.\n\n- **REQ-3** (FAIL)\n- **REQ-4** (NOT RUN)\n",
+			"## ⚠️ Requirements Without Tests\n\n- **REQ-9**: Passwords expire\n",
+		} {
+			if !strings.Contains(output, expected) {
+				t.Errorf("Missing %q in output:\n%s", expected, output)
+			}
+		}
+	})
+}
+
+// TestSuiteDescriptions tests suite descriptions from file comments and package doc summaries
+// This validates leading file comments, license headers, doc_test.go and grouping by package
+func TestSuiteDescriptions(t *testing.T) {
+	tempDir := writeSampleModule(t, map[string]string{
+		"store/store.go": `// Package store keeps orders in memory. It is not safe for concurrent use.
+package store
+`,
+		"store/orders_test.go": `// Copyright 2024 The Store Authors. All rights reserved.
+
+//go:build !race
+
+// Order placement and cancellation.
+
+package store_test
+
+import "testing"
+
+func TestPlace(t *testing.T) {}
+`,
+		"store/returns_test.go": `package store_test
+
+import "testing"
+
+func TestReturn(t *testing.T) {}
+`,
+		"billing/billing.go": `// Package billing charges customers.
+package billing
+`,
+		"billing/doc_test.go": `// Tests for billing cover invoicing and refunds.
+package billing_test
+`,
+		"billing/invoice_test.go": `// Invoices are issued monthly.
+package billing_test
+
+import "testing"
+
+func TestInvoice(t *testing.T) {}
+`,
+	})
+
+	testSuites, err := main.ParseTestSuites(tempDir)
+	if err != nil {
+		t.Fatalf("Failed to parse test suites: %v", err)
+	}
+
+	suites := make(map[string]main.TestSuite)
+	for _, ts := range testSuites {
+		suites[ts.Name] = ts
+	}
+
+	t.Run("file_descriptions", func(t *testing.T) {
+		expected := map[string]string{
+			"orders_test.go":  "Order placement and cancellation.",
+			"returns_test.go": "",
+			"invoice_test.go": "Invoices are issued monthly.",
+		}
+		for name, description := range expected {
+			if suites[name].CommentHeader != description {
+				t.Errorf("Expected %s description %q, got %q", name, description, suites[name].CommentHeader)
+			}
+		}
+	})
+
+	t.Run("package_docs", func(t *testing.T) {
+		if !strings.HasPrefix(suites["orders_test.go"].PackageDoc, "Package store keeps orders in memory.") {
+			t.Errorf("Expected the package under test's doc, got %q", suites["orders_test.go"].PackageDoc)
+		}
+		if !strings.HasPrefix(suites["invoice_test.go"].PackageDoc, "Tests for billing") {
+			t.Errorf("Expected the doc_test.go doc, got %q", suites["invoice_test.go"].PackageDoc)
+		}
+	})
+
+	t.Run("group_by_package", func(t *testing.T) {
+		outputFile := filepath.Join(t.TempDir(), "output.md")
+		if err := main.GenerateMarkdownReport(testSuites, nil, outputFile, main.ReportOptions{GroupByPackage: true}); err != nil {
+			t.Fatalf("Failed to generate markdown: %v", err)
+		}
+		content, err := os.ReadFile(outputFile)
+		if err != nil {
+			t.Fatalf("Failed to read output file: %v", err)
+		}
+		output := string(content)
+		for _, expected := range []string{
+			"## Package: testproject/store\n\nPackage store keeps orders in memory.\n\n### Test Suite: orders_test.go\n\n**Suite Description:**\n\nOrder placement and cancellation.\n\n",
+			"### Test Suite: returns_test.go",
+			"## Package: testproject/billing\n\nTests for billing cover invoicing and refunds.\n\n",
+		} {
+			if !strings.Contains(output, expected) {
+				t.Errorf("Missing %q in output:\n%s", expected, output)
+			}
+		}
+		if strings.Count(output, "## Package: testproject/store") != 1 {
+			t.Errorf("Expected a single store package heading:\n%s", output)
+		}
+	})
+}
+
+// TestDocCommentDetails tests rendering of full doc comments as Markdown in collapsible blocks
+// This validates paragraphs, lists, code blocks, headings, doc links and that tags are left out
+func TestDocCommentDetails(t *testing.T) {
+	tempDir := writeSampleModule(t, map[string]string{
+		"details_test.go": `package testproject_test
+
+import "testing"
+
+// TestCheckout walks through the checkout flow.
+//
+// The cart is priced with [strings.Repeat] semantics:
+//   - items are summed
+//   - discounts apply last
+//
+// # Setup
+//
+// A cart is created like:
+//
+//	cart := NewCart()
+//
+// @owner: shop-team
+func TestCheckout(t *testing.T) {
+	// Single-line comments stay in the table only
+	t.Run("empty", func(t *testing.T) {})
+}
+`,
+	})
+
+	testSuites, err := main.ParseTestSuites(tempDir)
+	if err != nil {
+		t.Fatalf("Failed to parse test suites: %v", err)
+	}
+
+	outputFile := filepath.Join(t.TempDir(), "output.md")
+	if err := main.GenerateMarkdownReport(testSuites, nil, outputFile, main.ReportOptions{Details: true}); err != nil {
+		t.Fatalf("Failed to generate markdown: %v", err)
+	}
+	content, err := os.ReadFile(outputFile)
+	if err != nil {
+		t.Fatalf("Failed to read output file: %v", err)
+	}
+	output := string(content)
+
+	t.Run("summary_in_table", func(t *testing.T) {
+		if !strings.Contains(output, "| TestCheckout walks through the checkout flow. `owner: shop-team` |") {
+			t.Errorf("Expected one-line summary in the table:\n%s", output)
+		}
+	})
+
+	t.Run("rendered_details", func(t *testing.T) {
+		for _, expected := range []string{
+			"<details>\n<summary>TestCheckout</summary>\n\nTestCheckout walks through the checkout flow.",
+			"[strings.Repeat](https://pkg.go.dev/strings#Repeat)",
+			"  - items are summed\n  - discounts apply last",
+			"#### Setup\n",
+			"\tcart := NewCart()",
+			"</details>",
+		} {
+			if !strings.Contains(output, expected) {
+				t.Errorf("Missing %q in output:\n%s", expected, output)
+			}
+		}
+		if strings.Contains(output, "@owner") {
+			t.Error("Tags should be left out of rendered doc comments")
+		}
+		if strings.Contains(output, "<summary>TestCheckout → empty</summary>") {
+			t.Error("Single-line comments should not get a details block")
+		}
+	})
+}
+
+// TestGoTestJSONInput tests reading test results from a go test -json event stream
+// This validates pass, fail and skip statuses, failure output and tests interrupted by a panic
+func TestGoTestJSONInput(t *testing.T) {
+	tempDir := writeSampleModule(t, map[string]string{
+		"results_test.go": `package testproject_test
+
+import "testing"
+
+// TestPasses passes
+func TestPasses(t *testing.T) {
+	t.Run("child", func(t *testing.T) {})
+}
+
+// TestFails fails
+func TestFails(t *testing.T) {
+	t.Errorf("expected %d, got %d", 1, 2)
+}
+
+// TestSkips is skipped
+func TestSkips(t *testing.T) {
+	t.Skip("not on this platform")
+}
+`,
+	})
+
+	t.Run("real_stream", func(t *testing.T) {
+		cmd := exec.Command("go", "test", "-json", "./...")
+		cmd.Dir = tempDir
+		out, _ := cmd.Output() // exits non-zero since TestFails fails
+
+		results, err := main.ParseGoTestJSON(strings.NewReader(string(out)))
+		if err != nil {
+			t.Fatalf("Failed to parse go test -json output: %v", err)
+		}
+
+		expected := map[string]string{
+			"testproject::TestPasses":       "PASS",
+			"testproject::TestPasses/child": "PASS",
+			"testproject::TestFails":        "FAIL",
+			"testproject::TestSkips":        "SKIP",
+		}
+		for key, status := range expected {
+			if results[key].Status != status {
+				t.Errorf("Expected %s to be %s, got %+v", key, status, results[key])
+			}
+		}
+
+		failure := results["testproject::TestFails"].Failure
+		if !strings.Contains(failure, "results_test.go:12: expected 1, got 2") || strings.Contains(failure, "--- FAIL") {
+			t.Errorf("Unexpected failure text %q", failure)
+		}
+		if !strings.Contains(results["testproject::TestSkips"].Failure, "not on this platform") {
+			t.Errorf("Expected skip message, got %q", results["testproject::TestSkips"].Failure)
+		}
+	})
+
+	t.Run("interrupted_tests", func(t *testing.T) {
+		stream := `{"Action":"start","Package":"example.com/pkg"}
+{"Action":"run","Package":"example.com/pkg","Test":"TestDone"}
+{"Action":"pass","Package":"example.com/pkg","Test":"TestDone","Elapsed":0.25}
+{"Action":"run","Package":"example.com/pkg","Test":"TestPanics"}
+{"Action":"output","Package":"example.com/pkg","Test":"TestPanics","Output":"panic: boom\n"}
+not a json line
+{"Action":"fail","Package":"example.com/pkg","Elapsed":0.3}
+`
+		results, err := main.ParseGoTestJSON(strings.NewReader(stream))
+		if err != nil {
+			t.Fatalf("Failed to parse stream: %v", err)
+		}
+		if results["example.com/pkg::TestDone"].Duration != "0.25s" {
+			t.Errorf("Unexpected duration %+v", results["example.com/pkg::TestDone"])
+		}
+		panicked := results["example.com/pkg::TestPanics"]
+		if panicked.Status != "FAIL" || panicked.Failure != "panic: boom" {
+			t.Errorf("Expected interrupted test to fail with its output, got %+v", panicked)
+		}
+	})
+
+	t.Run("empty_stream", func(t *testing.T) {
+		if _, err := main.ParseGoTestJSON(strings.NewReader("")); err == nil {
+			t.Error("Expected an error for a stream without events")
+		}
+		if _, err := main.ParseGoTestJSON(strings.NewReader("build failed\n")); err == nil {
+			t.Error("Expected an error for a stream without JSON events")
+		}
+	})
+}
+
+// TestMergeJUnitShards tests merging results from several JUnit files such as CI shards
+// This validates glob patterns, summed durations and conflicting results across files
+func TestMergeJUnitShards(t *testing.T) {
+	shard := func(cases string) string {
+		return `<?xml version="1.0" encoding="UTF-8"?>
+<testsuites>
+  <testsuite name="testproject">
+` + cases + `
+  </testsuite>
+</testsuites>
+`
+	}
+	tempDir := writeSampleModule(t, map[string]string{
+		"shards_test.go": `package testproject_test
+
+import "testing"
+
+func TestSplit(t *testing.T) {}
+
+func TestOnlyFirst(t *testing.T) {}
+
+func TestDisagree(t *testing.T) {}
+`,
+		"shards/shard-1.xml": shard(`    <testcase classname="testproject" name="TestSplit" time="0.5"></testcase>
+    <testcase classname="testproject" name="TestOnlyFirst" time="0.1"></testcase>
+    <testcase classname="testproject" name="TestDisagree" time="0.1"></testcase>`),
+		"shards/shard-2.xml": shard(`    <testcase classname="testproject" name="TestSplit" time="0.25"></testcase>
+    <testcase classname="testproject" name="TestDisagree" time="0.1">
+      <failure message="boom"></failure>
+    </testcase>`),
+		"extra.xml": shard(`    <testcase classname="testproject" name="TestOnlyFirst" time="0.1"></testcase>`),
+	})
+
+	results, err := main.ParseJUnitResults(filepath.Join(tempDir, "shards", "*.xml"), filepath.Join(tempDir, "extra.xml"))
+	if err != nil {
+		t.Fatalf("Failed to parse JUnit results: %v", err)
+	}
+
+	t.Run("summed_durations", func(t *testing.T) {
+		split := results["testproject::TestSplit"]
+		if split.Status != "PASS" || split.Duration != "0.75s" || len(split.Conflicts) != 0 {
+			t.Errorf("Expected merged passing record of 0.75s, got %+v", split)
+		}
+		if only := results["testproject::TestOnlyFirst"]; only.Duration != "0.2s" || len(only.Conflicts) != 0 {
+			t.Errorf("Expected repeated file results to merge, got %+v", only)
+		}
+	})
+
+	t.Run("conflicts", func(t *testing.T) {
+		disagree := results["testproject::TestDisagree"]
+		if disagree.Status != "FAIL" || disagree.Failure != "boom" {
+			t.Errorf("Expected the failing result to win, got %+v", disagree)
+		}
+		if len(disagree.Conflicts) != 2 || !strings.HasSuffix(disagree.Conflicts[0], "shard-1.xml: PASS") || !strings.HasSuffix(disagree.Conflicts[1], "shard-2.xml: FAIL") {
+			t.Errorf("Expected per-file statuses, got %v", disagree.Conflicts)
+		}
+
+		testSuites, err := main.ParseTestSuites(tempDir)
+		if err != nil {
+			t.Fatalf("Failed to parse test suites: %v", err)
+		}
+		outputFile := filepath.Join(t.TempDir(), "output.md")
+		if err := main.GenerateMarkdownReport(testSuites, results, outputFile, main.ReportOptions{}); err != nil {
+			t.Fatalf("Failed to generate markdown: %v", err)
+		}
+		content, err := os.ReadFile(outputFile)
+		if err != nil {
+			t.Fatalf("Failed to read output file: %v", err)
+		}
+		output := string(content)
+		for _, expected := range []string{
+			"## ⚠️ Conflicting Results",
+			"- `testproject::TestDisagree`: ",
+			"| TestDisagree | ❌ FAIL ⚠️ | 0.2s |",
+		} {
+			if !strings.Contains(output, expected) {
+				t.Errorf("Missing %q in output:\n%s", expected, output)
+			}
+		}
+	})
+
+	t.Run("overlapping_patterns", func(t *testing.T) {
+		shard1 := filepath.Join(tempDir, "shards", "shard-1.xml")
+		results, err := main.ParseJUnitResults(shard1, filepath.Join(tempDir, "shards", "*.xml"))
+		if err != nil {
+			t.Fatalf("Failed to parse JUnit results: %v", err)
+		}
+		if split := results["testproject::TestSplit"]; split.Duration != "0.75s" || len(split.Conflicts) != 0 {
+			t.Errorf("Expected each file to be read once, got %+v", split)
+		}
+		if disagree := results["testproject::TestDisagree"]; len(disagree.Conflicts) != 2 {
+			t.Errorf("Expected one status per file, got %v", disagree.Conflicts)
+		}
+	})
+
+	t.Run("missing_files", func(t *testing.T) {
+		results, err := main.ParseJUnitResults(filepath.Join(tempDir, "missing.xml"), filepath.Join(tempDir, "extra.xml"))
+		if err == nil {
+			t.Error("Expected an error for a missing file")
+		}
+		if _, ok := results["testproject::TestOnlyFirst"]; !ok {
+			t.Error("Expected results from the files that could be read")
+		}
+	})
+}
+
+// TestFlakyTestDetection tests classification of tests rerun after failing
+// This validates attempt tracking, the FLAKY status and the flaky tests section of the report
+func TestFlakyTestDetection(t *testing.T) {
+	tempDir := writeSampleModule(t, map[string]string{
+		"flaky_test.go": `package testproject_test
+
+import "testing"
+
+func TestStable(t *testing.T) {}
+
+func TestFlaky(t *testing.T) {}
+
+func TestBroken(t *testing.T) {}
+`,
+		"junit.xml": `<?xml version="1.0" encoding="UTF-8"?>
+<testsuites>
+  <testsuite name="testproject">
+    <testcase classname="testproject" name="TestStable" time="0.1"></testcase>
+    <testcase classname="testproject" name="TestFlaky" time="0.1">
+      <failure message="timed out waiting for server"></failure>
+    </testcase>
+    <testcase classname="testproject" name="TestBroken" time="0.1">
+      <failure message="first failure"></failure>
+    </testcase>
+    <testcase classname="testproject" name="TestFlaky" time="0.2"></testcase>
+    <testcase classname="testproject" name="TestBroken" time="0.1">
+      <failure message="second failure"></failure>
+    </testcase>
+  </testsuite>
+</testsuites>
+`,
+	})
+
+	results, err := main.ParseJUnitResults(filepath.Join(tempDir, "junit.xml"))
+	if err != nil {
+		t.Fatalf("Failed to parse JUnit results: %v", err)
+	}
+
+	t.Run("classification", func(t *testing.T) {
+		expected := map[string]struct {
+			status   string
+			attempts int
+			failure  string
+		}{
+			"testproject::TestStable": {"PASS", 1, ""},
+			"testproject::TestFlaky":  {"FLAKY", 2, "timed out waiting for server"},
+			"testproject::TestBroken": {"FAIL", 2, "second failure"},
+		}
+		for key, want := range expected {
+			rec := results[key]
+			if rec.Status != want.status || rec.Attempts != want.attempts || rec.Failure != want.failure {
+				t.Errorf("Expected %s to be %+v, got %+v", key, want, rec)
+			}
+		}
+	})
+
+	t.Run("go_test_json_reruns", func(t *testing.T) {
+		stream := `{"Action":"run","Package":"p","Test":"TestRetried"}
+{"Action":"output","Package":"p","Test":"TestRetried","Output":"    x_test.go:3: flake\n"}
+{"Action":"fail","Package":"p","Test":"TestRetried","Elapsed":0.1}
+{"Action":"run","Package":"p","Test":"TestRetried"}
+{"Action":"pass","Package":"p","Test":"TestRetried","Elapsed":0.1}
+`
+		results, err := main.ParseGoTestJSON(strings.NewReader(stream))
+		if err != nil {
+			t.Fatalf("Failed to parse stream: %v", err)
+		}
+		if rec := results["p::TestRetried"]; rec.Status != "FLAKY" || rec.Attempts != 2 || rec.Failure != "x_test.go:3: flake" {
+			t.Errorf("Expected a flaky rerun, got %+v", rec)
+		}
+	})
+
+	t.Run("flaky_section", func(t *testing.T) {
+		testSuites, err := main.ParseTestSuites(tempDir)
+		if err != nil {
+			t.Fatalf("Failed to parse test suites: %v", err)
+		}
+		outputFile := filepath.Join(t.TempDir(), "output.md")
+		if err := main.GenerateMarkdownReport(testSuites, results, outputFile, main.ReportOptions{FailSnippet: 300}); err != nil {
+			t.Fatalf("Failed to generate markdown: %v", err)
+		}
+		content, err := os.ReadFile(outputFile)
+		if err != nil {
+			t.Fatalf("Failed to read output file: %v", err)
+		}
+		output := string(content)
+		for _, expected := range []string{
+			"## 🔁 Flaky Tests\n\nThese tests failed and then passed when rerun.",
+			"| `testproject::TestFlaky` | 2 | timed out waiting for server |",
+			"| TestStable | ✅ PASS | 0.1s |",
+			"| TestFlaky | 🔁 FLAKY (2 attempts) | 0.3s |",
+			"| TestBroken | ❌ FAIL (2 attempts) | 0.2s |",
+		} {
+			if !strings.Contains(output, expected) {
+				t.Errorf("Missing %q in output:\n%s", expected, output)
+			}
+		}
+	})
+}
+
+// TestReconcileUnmatchedResults tests that JUnit records missing from the source tree are still reported
+// This validates attaching dynamic tests under their closest parent and the unmatched summary per package
+func TestReconcileUnmatchedResults(t *testing.T) {
+	tempDir := writeSampleModule(t, map[string]string{
+		"dynamic_test.go": `package testproject_test
+
+import (
+	"os"
+	"testing"
+)
+
+func TestFixtures(t *testing.T) {
+	t.Run("static", func(t *testing.T) {})
+
+	entries, _ := os.ReadDir("testdata")
+	for _, e := range entries {
+		t.Run(e.Name(), func(t *testing.T) {})
+	}
+}
+`,
+		"junit.xml": `<?xml version="1.0" encoding="UTF-8"?>
+<testsuites>
+  <testsuite name="testproject">
+    <testcase classname="testproject" name="TestFixtures" time="0.1"></testcase>
+    <testcase classname="testproject" name="TestFixtures/static" time="0.1"></testcase>
+    <testcase classname="testproject" name="TestFixtures/static/deep" time="0.1"></testcase>
+    <testcase classname="testproject" name="TestFixtures/a.json" time="0.1"></testcase>
+    <testcase classname="testproject" name="TestFixtures/a.json/nested" time="0.1">
+      <failure message="bad fixture"></failure>
+    </testcase>
+    <testcase classname="testproject" name="TestGenerated" time="0.1"></testcase>
+  </testsuite>
+  <testsuite name="testproject/other">
+    <testcase classname="testproject/other" name="TestElsewhere" time="0.1"></testcase>
+  </testsuite>
+</testsuites>
+`,
+	})
+
+	testSuites, err := main.ParseTestSuites(tempDir)
+	if err != nil {
+		t.Fatalf("Failed to parse test suites: %v", err)
+	}
+	results, err := main.ParseJUnitResults(filepath.Join(tempDir, "junit.xml"))
+	if err != nil {
+		t.Fatalf("Failed to parse JUnit results: %v", err)
+	}
+	reconciled := main.ReconcileResults(testSuites, results)
+
+	t.Run("attached_under_parents", func(t *testing.T) {
+		fixtures := reconciled[0].TestUnits[0]
+		var names []string
+		var dynamic []string
+		var collect func(units []main.TestUnit)
+		collect = func(units []main.TestUnit) {
+			for _, tu := range units {
+				names = append(names, tu.MachineTestName)
+				if tu.Dynamic {
+					dynamic = append(dynamic, tu.TestName)
+				}
+				collect(tu.Subtests)
+			}
+		}
+		collect(fixtures.Subtests)
+
+		// The e.Name() subtest predicted from source stays as written
+		expected := []string{"TestFixtures/static", "TestFixtures/static/deep", "TestFixtures/e.Name()", "TestFixtures/a.json", "TestFixtures/a.json/nested"}
+		if strings.Join(names, ",") != strings.Join(expected, ",") {
+			t.Errorf("Expected subtests %v, got %v", expected, names)
+		}
+		if strings.Join(dynamic, ",") != "deep,a.json,nested" {
+			t.Errorf("Unexpected dynamic tests %v", dynamic)
+		}
+	})
+
+	t.Run("orphans_in_own_suite", func(t *testing.T) {
+		if len(reconciled) != 3 {
+			t.Fatalf("Expected suites for the orphaned results, got %d", len(reconciled))
+		}
+		for i, expected := range []string{"testproject::TestGenerated", "testproject/other::TestElsewhere"} {
+			ts := reconciled[i+1]
+			if ts.Name != "JUnit results without source" || ts.PackageName+"::"+ts.TestUnits[0].MachineTestName != expected {
+				t.Errorf("Unexpected orphan suite %+v", ts)
+			}
+		}
+	})
+
+	t.Run("report", func(t *testing.T) {
+		outputFile := filepath.Join(t.TempDir(), "output.md")
+		if err := main.GenerateMarkdownReport(reconciled, results, outputFile, main.ReportOptions{FailSnippet: 300}); err != nil {
+			t.Fatalf("Failed to generate markdown: %v", err)
+		}
+		content, err := os.ReadFile(outputFile)
+		if err != nil {
+			t.Fatalf("Failed to read output file: %v", err)
+		}
+		output := string(content)
+		for _, expected := range []string{
+			"| TestFixtures → a.json → nested | ❌ FAIL | 0.1s | _dynamic: not found in source_ | bad fixture |",
+			"## 🔍 Unmatched Test Results",
+			"| testproject | 4 |",
+			"| testproject/other | 1 |",
+		} {
+			if !strings.Contains(output, expected) {
+				t.Errorf("Missing %q in output:\n%s", expected, output)
+			}
+		}
+	})
+}
+
+// TestJUnitErrorsAndOutput tests parsing of <error>, <system-out> and <system-err> JUnit elements
+// This validates the ERROR status, failure bodies and captured output blocks limited by the snippet size
+func TestJUnitErrorsAndOutput(t *testing.T) {
+	tempDir := writeSampleModule(t, map[string]string{
+		"output_test.go": `package testproject_test
+
+import "testing"
+
+func TestPanics(t *testing.T) {}
+
+func TestAsserts(t *testing.T) {}
+
+func TestPasses(t *testing.T) {}
+`,
+		"junit.xml": `<?xml version="1.0" encoding="UTF-8"?>
+<testsuites>
+  <testsuite name="testproject">
+    <testcase classname="testproject" name="TestPanics" time="0.1">
+      <error message="Panic">panic: runtime error: index out of range [recovered]</error>
+      <system-out>starting server on :8080</system-out>
+    </testcase>
+    <testcase classname="testproject" name="TestAsserts" time="0.1">
+      <failure message="Failed">output_test.go:7: expected 1, got 2
+with a very long explanation that goes past the snippet limit</failure>
+      <system-err>warning: using defaults</system-err>
+    </testcase>
+    <testcase classname="testproject" name="TestPasses" time="0.1">
+      <system-out>quiet</system-out>
+    </testcase>
+  </testsuite>
+</testsuites>
+`,
+	})
+
+	results, err := main.ParseJUnitResults(filepath.Join(tempDir, "junit.xml"))
+	if err != nil {
+		t.Fatalf("Failed to parse JUnit results: %v", err)
+	}
+
+	t.Run("parsed_elements", func(t *testing.T) {
+		panics := results["testproject::TestPanics"]
+		if panics.Status != "ERROR" || panics.Failure != "Panic" || !strings.HasPrefix(panics.Details, "panic: runtime error") || panics.Stdout != "starting server on :8080" {
+			t.Errorf("Unexpected error record %+v", panics)
+		}
+		asserts := results["testproject::TestAsserts"]
+		if asserts.Status != "FAIL" || !strings.HasPrefix(asserts.Details, "output_test.go:7") || asserts.Stderr != "warning: using defaults" {
+			t.Errorf("Unexpected failure record %+v", asserts)
+		}
+	})
+
+	testSuites, err := main.ParseTestSuites(tempDir)
+	if err != nil {
+		t.Fatalf("Failed to parse test suites: %v", err)
+	}
+	generate := func(t *testing.T, opts main.ReportOptions) string {
+		t.Helper()
+		outputFile := filepath.Join(t.TempDir(), "output.md")
+		if err := main.GenerateMarkdownReport(testSuites, results, outputFile, opts); err != nil {
+			t.Fatalf("Failed to generate markdown: %v", err)
+		}
+		content, err := os.ReadFile(outputFile)
+		if err != nil {
+			t.Fatalf("Failed to read output file: %v", err)
+		}
+		return string(content)
+	}
+
+	t.Run("output_blocks", func(t *testing.T) {
+		output := generate(t, main.ReportOptions{FailSnippet: 40})
+		for _, expected := range []string{
+			"| TestPanics | 💥 ERROR | 0.1s |  | Panic |",
+			"<summary>💥 TestPanics output</summary>",
+			"  **stdout:**\n\n  ```text\n  starting server on :8080\n  ```",
+			"<summary>❌ TestAsserts output</summary>",
+			"  **stderr:**\n\n  ```text\n  warning: using defaults\n  ```",
+		} {
+			if !strings.Contains(output, expected) {
+				t.Errorf("Missing %q in output:\n%s", expected, output)
+			}
+		}
+		if strings.Contains(output, "past the snippet limit") {
+			t.Error("Expected output to be limited to the snippet size")
+		}
+		if strings.Contains(output, "quiet") {
+			t.Error("Passing tests should not show their output")
+		}
+	})
+
+	t.Run("hidden_without_snippet", func(t *testing.T) {
+		if output := generate(t, main.ReportOptions{}); strings.Contains(output, "<details>") {
+			t.Errorf("Expected no output blocks with a zero snippet limit:\n%s", output)
+		}
+	})
+}
+
+// TestFailureDetails tests that failure output honours the snippet limit and is shown in full below each table
+// This validates rune-safe truncation, cutting at line breaks and file:line references in the Failures section
+func TestFailureDetails(t *testing.T) {
+	tempDir := writeSampleModule(t, map[string]string{
+		"pkg/failing_test.go": `package pkg_test
+
+import "testing"
+
+func TestUnicode(t *testing.T) {}
+
+func TestMultiline(t *testing.T) {
+	t.Run("case", func(t *testing.T) {})
+}
+`,
+		"junit.xml": `<?xml version="1.0" encoding="UTF-8"?>
+<testsuites>
+  <testsuite name="testproject/pkg">
+    <testcase classname="testproject/pkg" name="TestUnicode" time="0.1">
+      <failure message="ünïcödé ✓✓✓✓✓✓✓✓✓✓ mismatch"></failure>
+    </testcase>
+    <testcase classname="testproject/pkg" name="TestMultiline" time="0.1"></testcase>
+    <testcase classname="testproject/pkg" name="TestMultiline/case" time="0.1">
+      <failure message="Failed">    failing_test.go:8: first line of the failure
+    helpers_test.go:21: second line of the failure
+    third line that is cut</failure>
+    </testcase>
+  </testsuite>
+</testsuites>
+`,
+	})
+
+	testSuites, err := main.ParseTestSuites(tempDir)
+	if err != nil {
+		t.Fatalf("Failed to parse test suites: %v", err)
+	}
+	results, err := main.ParseJUnitResults(filepath.Join(tempDir, "junit.xml"))
+	if err != nil {
+		t.Fatalf("Failed to parse JUnit results: %v", err)
+	}
+
+	generate := func(t *testing.T, snippet int) string {
+		t.Helper()
+		outputFile := filepath.Join(tempDir, "TESTS.md")
+		if err := main.GenerateMarkdownReport(testSuites, results, outputFile, main.ReportOptions{SourceDir: tempDir, FailSnippet: snippet}); err != nil {
+			t.Fatalf("Failed to generate markdown: %v", err)
+		}
+		content, err := os.ReadFile(outputFile)
+		if err != nil {
+			t.Fatalf("Failed to read output file: %v", err)
+		}
+		return string(content)
+	}
+
+	t.Run("rune_safe_truncation", func(t *testing.T) {
+		output := generate(t, 12)
+		if !utf8.ValidString(output) {
+			t.Error("Expected valid UTF-8 after truncation")
+		}
+		if !strings.Contains(output, "| ünïcödé ✓✓✓✓… |") {
+			t.Errorf("Expected failure truncated to 12 runes:\n%s", output)
+		}
+	})
+
+	t.Run("failures_section", func(t *testing.T) {
+		output := generate(t, 110)
+		for _, expected := range []string{
+			"**Failures:**\n\n- ❌ **TestUnicode**\n",
+			"- ❌ **TestMultiline → case** ([failing_test.go:8](pkg/failing_test.go#L8), [helpers_test.go:21](pkg/helpers_test.go#L21))",
+			"  ```text\n  Failed\n  failing_test.go:8: first line of the failure\n      helpers_test.go:21: second line of the failure…\n  ```",
+		} {
+			if !strings.Contains(output, expected) {
+				t.Errorf("Missing %q in output:\n%s", expected, output)
+			}
+		}
+		if strings.Contains(output, "third line") {
+			t.Error("Expected failure details cut at a line break within the snippet limit")
+		}
+	})
+
+	t.Run("hidden_with_zero_snippet", func(t *testing.T) {
+		output := generate(t, 0)
+		if strings.Contains(output, "**Failures:**") || strings.Contains(output, "mismatch") {
+			t.Errorf("Expected failures to be hidden:\n%s", output)
+		}
+	})
+}
+
+// TestJSONReport tests the machine-readable JSON report of the merged test tree
+// This validates statuses, failures, positions and tags in the output and that it follows the published schema
+func TestJSONReport(t *testing.T) {
+	tempDir := writeSampleModule(t, map[string]string{
+		"json_test.go": `package testproject_test
+
+import "testing"
+
+// TestOrders checks order handling
+// @owner: shop-team
+// @custom: kept in JSON
+func TestOrders(t *testing.T) {
+	// Rejects empty orders
+	t.Run("empty", func(t *testing.T) {})
+}
+
+func BenchmarkOrders(b *testing.B) {
+	b.Run("small", func(b *testing.B) {})
+}
+`,
+		"junit.xml": `<?xml version="1.0" encoding="UTF-8"?>
+<testsuites>
+  <testsuite name="testproject">
+    <testcase classname="testproject" name="TestOrders" time="0.2"></testcase>
+    <testcase classname="testproject" name="TestOrders/empty" time="0.1">
+      <failure message="accepted an empty order"></failure>
+    </testcase>
+  </testsuite>
+</testsuites>
+`,
+	})
+
+	testSuites, err := main.ParseTestSuites(tempDir)
+	if err != nil {
+		t.Fatalf("Failed to parse test suites: %v", err)
+	}
+	results, err := main.ParseJUnitResults(filepath.Join(tempDir, "junit.xml"))
+	if err != nil {
+		t.Fatalf("Failed to parse JUnit results: %v", err)
+	}
+
+	outputFile := filepath.Join(t.TempDir(), "report.json")
+	if err := main.GenerateJSONReport(testSuites, results, outputFile); err != nil {
+		t.Fatalf("Failed to generate JSON report: %v", err)
+	}
+	content, err := os.ReadFile(outputFile)
+	if err != nil {
+		t.Fatalf("Failed to read output file: %v", err)
+	}
+
+	t.Run("merged_tree", func(t *testing.T) {
+		var report main.Report
+		if err := json.Unmarshal(content, &report); err != nil {
+			t.Fatalf("Failed to decode JSON report: %v", err)
+		}
+		if report.SchemaVersion != main.ReportSchemaVersion || len(report.Suites) != 1 {
+			t.Fatalf("Unexpected report %+v", report)
+		}
+		orders := report.Suites[0].Tests[0]
+		if orders.Status != "PASS" || orders.Seconds != 0.2 || orders.File != "json_test.go" || orders.Line != 8 || orders.Kind != "Test" {
+			t.Errorf("Unexpected test %+v", orders)
+		}
+		if orders.Summary != "TestOrders checks order handling" || orders.Tags["custom"][0] != "kept in JSON" {
+			t.Errorf("Expected summary and unknown tags, got %+v", orders)
+		}
+		empty := orders.Subtests[0]
+		if empty.MachineName != "TestOrders/empty" || empty.Status != "FAIL" || empty.Failure != "accepted an empty order" || empty.ClassName != "testproject" {
+			t.Errorf("Unexpected subtest %+v", empty)
+		}
+		if empty.Kind != "Test" {
+			t.Errorf("Expected subtest of a test to be a Test, got %q", empty.Kind)
+		}
+		bench := report.Suites[0].Tests[1]
+		if bench.Kind != "Benchmark" || len(bench.Subtests) != 1 || bench.Subtests[0].Kind != "Benchmark" {
+			t.Errorf("Expected sub-benchmark to keep its parent's kind, got %+v", bench)
+		}
+	})
+
+	t.Run("follows_schema", func(t *testing.T) {
+		schemaContent, err := os.ReadFile(filepath.Join("schema", "report.schema.json"))
+		if err != nil {
+			t.Fatalf("Failed to read schema: %v", err)
+		}
+		var schema struct {
+			Properties map[string]json.RawMessage
+			Defs       map[string]struct {
+				Required   []string
+				Properties map[string]json.RawMessage
+			} `json:"$defs"`
+		}
+		if err := json.Unmarshal(schemaContent, &schema); err != nil {
+			t.Fatalf("Failed to decode schema: %v", err)
+		}
+
+		// check verifies an object only has properties of the schema definition
+		// and all of its required ones
+		var check func(obj map[string]interface{}, def string)
+		check = func(obj map[string]interface{}, def string) {
+			for key := range obj {
+				if _, ok := schema.Defs[def].Properties[key]; !ok {
+					t.Errorf("Property %q of %s is not in the schema", key, def)
+				}
+			}
+			for _, key := range schema.Defs[def].Required {
+				if _, ok := obj[key]; !ok {
+					t.Errorf("Required property %q of %s is missing", key, def)
+				}
+			}
+			if def == "suite" {
+				for _, test := range obj["tests"].([]interface{}) {
+					check(test.(map[string]interface{}), "test")
+				}
+			}
+			if def == "test" {
+				for _, sub := range obj["subtests"].([]interface{}) {
+					check(sub.(map[string]interface{}), "test")
+				}
+			}
+		}
+
+		var report map[string]interface{}
+		if err := json.Unmarshal(content, &report); err != nil {
+			t.Fatalf("Failed to decode JSON report: %v", err)
+		}
+		for key := range report {
+			if _, ok := schema.Properties[key]; !ok {
+				t.Errorf("Property %q is not in the schema", key)
+			}
+		}
+		for _, suite := range report["suites"].([]interface{}) {
+			check(suite.(map[string]interface{}), "suite")
+		}
+	})
+}
+
+// TestHTMLReport tests the standalone HTML report
+// This validates that the page is self-contained, offers status filters and search data, and nests and escapes tests
+func TestHTMLReport(t *testing.T) {
+	tempDir := writeSampleModule(t, map[string]string{
+		"html_test.go": `package testproject_test
+
+import "testing"
+
+// TestCheckout checks <b>checkout</b> & payment
+// @owner: shop-team
+func TestCheckout(t *testing.T) {
+	// Pays by card
+	t.Run("card", func(t *testing.T) {})
+	// Pays by invoice
+	t.Run("invoice", func(t *testing.T) {})
+}
+`,
+		"junit.xml": `<?xml version="1.0" encoding="UTF-8"?>
+<testsuites>
+  <testsuite name="testproject">
+    <testcase classname="testproject" name="TestCheckout" time="0.3"></testcase>
+    <testcase classname="testproject" name="TestCheckout/card" time="0.1"></testcase>
+    <testcase classname="testproject" name="TestCheckout/invoice" time="0.2">
+      <failure message="invoice total &lt;0&gt;">html_test.go:11: total mismatch</failure>
+    </testcase>
+  </testsuite>
+</testsuites>
+`,
+	})
+
+	testSuites, err := main.ParseTestSuites(tempDir)
+	if err != nil {
+		t.Fatalf("Failed to parse test suites: %v", err)
+	}
+	results, err := main.ParseJUnitResults(filepath.Join(tempDir, "junit.xml"))
+	if err != nil {
+		t.Fatalf("Failed to parse JUnit results: %v", err)
+	}
+
+	outputFile := filepath.Join(t.TempDir(), "report.html")
+	if err := main.GenerateHTMLReport(testSuites, results, outputFile, main.ReportOptions{FailSnippet: 300}); err != nil {
+		t.Fatalf("Failed to generate HTML report: %v", err)
+	}
+	content, err := os.ReadFile(outputFile)
+	if err != nil {
+		t.Fatalf("Failed to read output file: %v", err)
+	}
+	page := string(content)
+
+	t.Run("self_contained", func(t *testing.T) {
+		for _, unexpected := range []string{"<link", "src=", "http://", "https://", "@import"} {
+			if strings.Contains(page, unexpected) {
+				t.Errorf("Expected no external resources, found %q", unexpected)
+			}
+		}
+		if !strings.Contains(page, "<style>") || !strings.Contains(page, "<script>") {
+			t.Error("Expected inline CSS and JavaScript")
+		}
+	})
+
+	t.Run("status_filters", func(t *testing.T) {
+		for _, expected := range []string{
+			`data-status="PASS" aria-pressed="true"><span class="status pass">✅ PASS</span> 2</button>`,
+			`data-status="FAIL" aria-pressed="true"><span class="status fail">❌ FAIL</span> 1</button>`,
+			`placeholder="Search 3 tests by name or description"`,
+		} {
+			if !strings.Contains(page, expected) {
+				t.Errorf("Expected filter %q in report", expected)
+			}
+		}
+		if strings.Contains(page, `data-status="SKIP" aria-pressed`) {
+			t.Error("Expected no filter for statuses without tests")
+		}
+	})
+
+	t.Run("test_tree", func(t *testing.T) {
+		parent := strings.Index(page, `data-text="testcheckout testcheckout`)
+		card := strings.Index(page, `data-text="card testcheckout/card pays by card`)
+		invoice := strings.Index(page, `data-status="FAIL" data-text="invoice testcheckout/invoice`)
+		if parent < 0 || card < parent || invoice < card {
+			t.Fatalf("Expected subtests nested after their parent, got %d, %d, %d", parent, card, invoice)
+		}
+		if !strings.Contains(page[parent:card], `<ul class="tests">`) {
+			t.Error("Expected subtests in a collapsible list under the parent")
+		}
+		if !strings.Contains(page, `<span class="tag">owner: shop-team</span>`) {
+			t.Error("Expected tag badge in report")
+		}
+	})
+
+	t.Run("failure_details", func(t *testing.T) {
+		expected := "<details class=\"failure\">\n  <summary>Failure details</summary><pre>invoice total &lt;0&gt;</pre><pre>html_test.go:11: total mismatch</pre>"
+		if !strings.Contains(page, expected) {
+			t.Errorf("Expected expandable failure details %q in report", expected)
+		}
+		if strings.Contains(page, "<b>checkout</b>") || !strings.Contains(page, "&lt;b&gt;checkout&lt;/b&gt; &amp; payment") {
+			t.Error("Expected comments to be HTML-escaped")
+		}
+	})
+}
+
+// TestTemplateReport tests rendering the report model with the built-in and user-supplied templates
+// This validates the default Markdown layout, the helper funcs and that HTML templates escape their output
+func TestTemplateReport(t *testing.T) {
+	tempDir := writeSampleModule(t, map[string]string{
+		"tmpl_test.go": `package testproject_test
+
+import (
+	"fmt"
+	"testing"
+)
+
+// TestLookup finds *users* by id
+func TestLookup(t *testing.T) {
+	// Unknown ids are rejected
+	t.Run("unknown_id", func(t *testing.T) {})
+}
+
+// BenchmarkLookup measures lookups
+func BenchmarkLookup(b *testing.B) {}
+
+// ExampleLookup prints a user
+func ExampleLookup() {
+	fmt.Println("alice")
+	// Output: alice
+}
+`,
+		"junit.xml": `<?xml version="1.0" encoding="UTF-8"?>
+<testsuites>
+  <testsuite name="testproject">
+    <testcase classname="testproject" name="TestLookup" time="75.4"></testcase>
+    <testcase classname="testproject" name="TestLookup/unknown_id" time="0.1234">
+      <failure message="got | want &lt;nil&gt;"></failure>
+    </testcase>
+  </testsuite>
+</testsuites>
+`,
+	})
+
+	testSuites, err := main.ParseTestSuites(tempDir)
+	if err != nil {
+		t.Fatalf("Failed to parse test suites: %v", err)
+	}
+	results, err := main.ParseJUnitResults(filepath.Join(tempDir, "junit.xml"))
+	if err != nil {
+		t.Fatalf("Failed to parse JUnit results: %v", err)
+	}
+	outDir := t.TempDir()
+	opts := main.ReportOptions{FailSnippet: 300}
+
+	// render writes a template to a file and renders the report with it
+	render := func(t *testing.T, name, tmpl string) string {
+		t.Helper()
+		path := filepath.Join(outDir, name)
+		if err := os.WriteFile(path, []byte(tmpl), 0644); err != nil {
+			t.Fatalf("Failed to write template: %v", err)
+		}
+		out := filepath.Join(outDir, "out-"+name)
+		if err := main.GenerateTemplateReport(testSuites, results, out, path, opts); err != nil {
+			t.Fatalf("Failed to render template: %v", err)
+		}
+		content, err := os.ReadFile(out)
+		if err != nil {
+			t.Fatalf("Failed to read output file: %v", err)
+		}
+		return string(content)
+	}
+
+	t.Run("default_template", func(t *testing.T) {
+		out := filepath.Join(outDir, "TESTS.md")
+		if err := main.GenerateTemplateReport(testSuites, results, out, "default", opts); err != nil {
+			t.Fatalf("Failed to render default template: %v", err)
+		}
+		content, err := os.ReadFile(out)
+		if err != nil {
+			t.Fatalf("Failed to read output file: %v", err)
+		}
+		for _, expected := range []string{
+			"# Test Documentation Report\n\n## Summary\n\n",
+			"## Test Suite: tmpl_test.go\n\n| Test Path | Status | Duration | Description | Failure |",
+			"| TestLookup | ✅ PASS | 75.4s | TestLookup finds *users* by id |  |\n",
+			"| TestLookup → unknown_id | ❌ FAIL | 0.1234s | Unknown ids are rejected | got \\| want <nil> |\n",
+			"| **Total: 2** | ✅ 1 ❌ 1 | 1m15s |  |  |\n",
+			"**Failures:**\n\n- ❌ **TestLookup → unknown_id**\n",
+			"### Benchmarks\n\n| Test Path |",
+			"| BenchmarkLookup | ⚪ NOT RUN | - | BenchmarkLookup measures lookups |  |\n",
+			"### Examples\n\n",
+			"**ExampleLookup output:**\n\n```text\nalice\n```\n",
+		} {
+			if !strings.Contains(string(content), expected) {
+				t.Errorf("Expected %q in report, got:\n%s", expected, content)
+			}
+		}
+	})
+
+	t.Run("text_template", func(t *testing.T) {
+		content := render(t, "report.adoc", `= Tests ({{.Total}})
+{{range .Counts}}* {{icon .Status}} {{.Status}}: {{.Count}}
+{{end}}{{range .Suites}}{{range flatten .Tests}}{{.Depth}} {{.MachineName}} {{if .Duration}}{{duration .Seconds}}{{end}} {{truncate 6 .Failure}}
+{{end}}{{end}}`)
+		expected := "= Tests (4)\n* ❌ FAIL: 1\n* ✅ PASS: 1\n* ⚪ NOT RUN: 2\n" +
+			"0 TestLookup 1m15s \n1 TestLookup/unknown_id 0.123s got |…\n0 BenchmarkLookup  \n0 ExampleLookup  \n"
+		if content != expected {
+			t.Errorf("Expected %q, got %q", expected, content)
+		}
+	})
+
+	t.Run("html_template", func(t *testing.T) {
+		content := render(t, "report.html.tmpl", `{{range .Suites}}{{range flatten .Tests}}<p>{{.Failure}}</p>{{end}}{{end}}`)
+		if !strings.Contains(content, "<p>got | want &lt;nil&gt;</p>") {
+			t.Errorf("Expected escaped HTML, got %q", content)
+		}
+	})
+
+	t.Run("template_errors", func(t *testing.T) {
+		out := filepath.Join(outDir, "missing.md")
+		if err := main.GenerateTemplateReport(testSuites, results, out, filepath.Join(outDir, "missing.tmpl"), opts); err == nil {
+			t.Error("Expected an error for a missing template")
+		}
+		path := filepath.Join(outDir, "broken.tmpl")
+		if err := os.WriteFile(path, []byte("{{range .Suites}}"), 0644); err != nil {
+			t.Fatalf("Failed to write template: %v", err)
+		}
+		if err := main.GenerateTemplateReport(testSuites, results, out, path, opts); err == nil {
+			t.Error("Expected an error for an unparsable template")
+		}
+	})
+}
+
+// TestMarkdownGolden tests the Markdown report, rendered by the built-in template, against golden files
+// This validates every section of the report for each layout option, and that -template default renders the same
+func TestMarkdownGolden(t *testing.T) {
+	tempDir := writeSampleModule(t, map[string]string{
+		"parity_test.go": `// Package testproject is checked for report parity.
+package testproject_test
+
+import (
+	"fmt"
+	"testing"
+)
+
+// TestCheckout covers the checkout flow.
+//
+// It runs against an in-memory store:
+//
+//   - carts are reset between subtests
+func TestCheckout(t *testing.T) {
+	// Empty carts are rejected
+	t.Run("empty_cart", func(t *testing.T) {
+		t.Run("no_items", func(t *testing.T) {})
+	})
+	t.Run("paid", func(t *testing.T) {})
+}
+
+// TestRetry eventually passes
+func TestRetry(t *testing.T) {}
+
+func TestSharded(t *testing.T) {}
+
+// BenchmarkCheckout measures checkouts
+func BenchmarkCheckout(b *testing.B) {}
+
+// ExampleCheckout prints a receipt
+func ExampleCheckout() {
+	fmt.Println("paid")
+	// Output: paid
+}
+`,
+		"other/other.go": `// Package other holds a second suite.
+package other
+`,
+		"other/other_test.go": `package other_test
+
+import "testing"
+
+func TestElsewhere(t *testing.T) {}
+`,
+		"junit-1.xml": `<?xml version="1.0" encoding="UTF-8"?>
+<testsuites>
+  <testsuite name="testproject">
+    <testcase classname="testproject" name="TestCheckout" time="1.5"></testcase>
+    <testcase classname="testproject" name="TestCheckout/empty_cart" time="0.5">
+      <failure message="accepted an empty cart">parity_test.go:17: cart | total</failure>
+      <system-out>checking cart</system-out>
+    </testcase>
+    <testcase classname="testproject" name="TestCheckout/empty_cart/no_items" time="0.2"></testcase>
+    <testcase classname="testproject" name="TestCheckout/paid" time="0.3"></testcase>
+    <testcase classname="testproject" name="TestCheckout/generated" time="0.1"></testcase>
+    <testcase classname="testproject" name="TestRetry" time="0.1">
+      <failure message="timed out"></failure>
+    </testcase>
+    <testcase classname="testproject" name="TestRetry" time="0.1"></testcase>
+    <testcase classname="testproject" name="TestSharded" time="0.1"></testcase>
+  </testsuite>
+  <testsuite name="testproject/other">
+    <testcase classname="testproject/other" name="TestElsewhere" time="0.1"></testcase>
+  </testsuite>
+</testsuites>
+`,
+		"junit-2.xml": `<?xml version="1.0" encoding="UTF-8"?>
+<testsuites>
+  <testsuite name="testproject">
+    <testcase classname="testproject" name="TestSharded" time="0.1">
+      <failure message="shard failed"></failure>
+    </testcase>
+  </testsuite>
+</testsuites>
+`,
+	})
+
+	testSuites, err := main.ParseTestSuites(tempDir)
+	if err != nil {
+		t.Fatalf("Failed to parse test suites: %v", err)
+	}
+	results, err := main.ParseJUnitResults(filepath.Join(tempDir, "junit-*.xml"))
+	if err != nil {
+		t.Fatalf("Failed to parse JUnit results: %v", err)
+	}
+	testSuites = main.ReconcileResults(testSuites, results)
+	// Reports are written within the module so source links are stable
+	outDir := filepath.Join(tempDir, "reports")
+	if err := os.Mkdir(outDir, 0755); err != nil {
+		t.Fatalf("Failed to create report directory: %v", err)
+	}
+
+	for name, opts := range map[string]main.ReportOptions{
+		"plain":            {},
+		"fail_snippet":     {FailSnippet: 300, Slowest: 3},
+		"group_by_package": {GroupByPackage: true},
+		"details":          {Details: true},
+		"nested":           {Nested: true, FailSnippet: 300},
+		"all":              {SourceDir: tempDir, GroupByPackage: true, Details: true, Nested: true, FailSnippet: 300, Slowest: 5},
+	} {
+		t.Run(name, func(t *testing.T) {
+			markdownFile := filepath.Join(outDir, name+".md")
+			if err := main.GenerateMarkdownReport(testSuites, results, markdownFile, opts); err != nil {
+				t.Fatalf("Failed to generate markdown: %v", err)
+			}
+			content, err := os.ReadFile(markdownFile)
+			if err != nil {
+				t.Fatalf("Failed to read output file: %v", err)
+			}
+			// Conflicts name the JUnit files by their absolute path
+			output := strings.ReplaceAll(string(content), tempDir, "<module>")
+
+			golden := filepath.Join("testdata", "markdown", name+".md")
+			if *update {
+				if err := os.WriteFile(golden, []byte(output), 0644); err != nil {
+					t.Fatalf("Failed to update golden file: %v", err)
+				}
+			}
+			expected, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("Failed to read golden file: %v", err)
+			}
+			if output != string(expected) {
+				t.Errorf("Report differs from %s (run go test -update to accept):\n%s", golden, output)
+			}
+
+			templateFile := filepath.Join(outDir, name+"-template.md")
+			if err := main.GenerateTemplateReport(testSuites, results, templateFile, "default", opts); err != nil {
+				t.Fatalf("Failed to render default template: %v", err)
+			}
+			rendered, err := os.ReadFile(templateFile)
+			if err != nil {
+				t.Fatalf("Failed to read output file: %v", err)
+			}
+			if string(rendered) != string(content) {
+				t.Errorf("Expected -template default to render the Markdown report, got:\n%s", rendered)
+			}
+		})
+	}
+}
+
+// TestSummaryStatistics tests the summary block, the per-suite totals rows and the GitHub Actions outputs
+// This validates that counts, pass rate, duration, slowest and undocumented tests are computed from the merged results
+func TestSummaryStatistics(t *testing.T) {
+	tempDir := writeSampleModule(t, map[string]string{
+		"stats_test.go": `package testproject_test
+
+import "testing"
+
+// TestAccounts covers account handling
+func TestAccounts(t *testing.T) {
+	t.Run("open", func(t *testing.T) {})
+	t.Run("close", func(t *testing.T) {})
+	t.Run("freeze", func(t *testing.T) {})
+}
+
+// TestReports covers reporting
+func TestReports(t *testing.T) {}
+
+func TestExport(t *testing.T) {}
+
+func TestImport(t *testing.T) {}
+`,
+		"junit.xml": `<?xml version="1.0" encoding="UTF-8"?>
+<testsuites>
+  <testsuite name="testproject">
+    <testcase classname="testproject" name="TestAccounts" time="1.5"><failure message="close failed"></failure></testcase>
+    <testcase classname="testproject" name="TestAccounts/open" time="0.5"></testcase>
+    <testcase classname="testproject" name="TestAccounts/close" time="1"><failure message="close failed"></failure></testcase>
+    <testcase classname="testproject" name="TestAccounts/freeze" time="0"><skipped message="not supported"></skipped></testcase>
+    <testcase classname="testproject" name="TestReports" time="2.25"></testcase>
+    <testcase classname="testproject" name="TestExport" time="0.25"></testcase>
+  </testsuite>
+</testsuites>
+`,
+	})
+
+	testSuites, err := main.ParseTestSuites(tempDir)
+	if err != nil {
+		t.Fatalf("Failed to parse test suites: %v", err)
+	}
+	results, err := main.ParseJUnitResults(filepath.Join(tempDir, "junit.xml"))
+	if err != nil {
+		t.Fatalf("Failed to parse JUnit results: %v", err)
+	}
+
+	t.Run("compute_stats", func(t *testing.T) {
+		stats := main.ComputeStats(testSuites, results, 2)
+		if stats.Total != 7 || stats.Passed != 3 || stats.Failed != 2 || stats.Skipped != 1 || stats.NotRun != 1 || stats.Flaky != 0 {
+			t.Errorf("Unexpected counts %+v", stats)
+		}
+		if stats.Undocumented != 5 {
+			t.Errorf("Expected 5 undocumented tests, got %d", stats.Undocumented)
+		}
+		if stats.Seconds != 4 {
+			t.Errorf("Expected the duration of top-level tests only, got %v", stats.Seconds)
+		}
+		if rate := stats.PassRate(); rate != 60 {
+			t.Errorf("Expected 60%% pass rate, got %v", rate)
+		}
+		if len(stats.Slowest) != 2 || stats.Slowest[0].Path != "TestReports" || stats.Slowest[1].Path != "TestAccounts" {
+			t.Errorf("Unexpected slowest tests %+v", stats.Slowest)
+		}
+	})
+
+	t.Run("summary_block", func(t *testing.T) {
+		outputFile := filepath.Join(t.TempDir(), "output.md")
+		if err := main.GenerateMarkdownReport(testSuites, results, outputFile, main.ReportOptions{Slowest: 3}); err != nil {
+			t.Fatalf("Failed to generate markdown: %v", err)
+		}
+		content, err := os.ReadFile(outputFile)
+		if err != nil {
+			t.Fatalf("Failed to read output file: %v", err)
+		}
+		output := string(content)
+		for _, expected := range []string{
+			"# Test Documentation Report\n\n## Summary\n\n",
+			"| 7 | 3 | 2 | 0 | 1 | 1 | 60.0% | 4s |\n",
+			"📝 5 of 7 tests have no description.",
+			"**Slowest tests:**\n\n1. TestReports (2.25s)\n2. TestAccounts (1.5s)\n3. TestAccounts → close (1s)\n\n## Test Suite",
+			"| TestImport | ⚪ NOT RUN | - |  |  |\n| **Total: 7** | ✅ 3 ❌ 2 ⏭️ 1 ⚪ 1 | 4s |  |  |\n",
+		} {
+			if !strings.Contains(output, expected) {
+				t.Errorf("Missing %q in output:\n%s", expected, output)
+			}
+		}
+	})
+
+	t.Run("github_outputs", func(t *testing.T) {
+		outputFile := filepath.Join(t.TempDir(), "github_output")
+		if err := os.WriteFile(outputFile, []byte("earlier=1\n"), 0644); err != nil {
+			t.Fatalf("Failed to write output file: %v", err)
+		}
+		if err := main.WriteGitHubOutputs(outputFile, main.ComputeStats(testSuites, results, 0)); err != nil {
+			t.Fatalf("Failed to write outputs: %v", err)
+		}
+		content, err := os.ReadFile(outputFile)
+		if err != nil {
+			t.Fatalf("Failed to read output file: %v", err)
+		}
+		expected := "earlier=1\ntotal=7\npassed=3\nfailed=2\nflaky=0\nskipped=1\nnot_run=1\npass_rate=60.0\nduration_seconds=4\nundocumented=5\n"
+		if string(content) != expected {
+			t.Errorf("Expected outputs %q, got %q", expected, content)
+		}
+	})
+}
+
+// TestNestedLayout tests the nested Markdown layout with collapsible subtest blocks
+// This validates that subtests leave the suite table, roll up onto their parents and collapse when fully passing
+func TestNestedLayout(t *testing.T) {
+	tempDir := writeSampleModule(t, map[string]string{
+		"nested_test.go": `package testproject_test
+
+import "testing"
+
+// TestParser checks the parser
+func TestParser(t *testing.T) {
+	t.Run("numbers", func(t *testing.T) {
+		t.Run("int", func(t *testing.T) {})
+		t.Run("float", func(t *testing.T) {})
+	})
+	t.Run("strings", func(t *testing.T) {
+		t.Run("quoted", func(t *testing.T) {})
+		t.Run("raw", func(t *testing.T) {})
+	})
+}
+
+// TestLexer checks the lexer
+func TestLexer(t *testing.T) {
+	t.Run("tokens", func(t *testing.T) {})
+}
+`,
+		"junit.xml": `<?xml version="1.0" encoding="UTF-8"?>
+<testsuites>
+  <testsuite name="testproject">
+    <testcase classname="testproject" name="TestParser" time="0.4"><failure message="raw strings"></failure></testcase>
+    <testcase classname="testproject" name="TestParser/numbers" time="0.2"></testcase>
+    <testcase classname="testproject" name="TestParser/numbers/int" time="0.1"></testcase>
+    <testcase classname="testproject" name="TestParser/numbers/float" time="0.1"></testcase>
+    <testcase classname="testproject" name="TestParser/strings" time="0.2"><failure message="raw strings"></failure></testcase>
+    <testcase classname="testproject" name="TestParser/strings/quoted" time="0.1"></testcase>
+    <testcase classname="testproject" name="TestParser/strings/raw" time="0.1"><failure message="unterminated"></failure></testcase>
+    <testcase classname="testproject" name="TestLexer" time="0.1"></testcase>
+    <testcase classname="testproject" name="TestLexer/tokens" time="0.1"></testcase>
+  </testsuite>
+</testsuites>
+`,
+	})
+
+	testSuites, err := main.ParseTestSuites(tempDir)
+	if err != nil {
+		t.Fatalf("Failed to parse test suites: %v", err)
+	}
+	results, err := main.ParseJUnitResults(filepath.Join(tempDir, "junit.xml"))
+	if err != nil {
+		t.Fatalf("Failed to parse JUnit results: %v", err)
+	}
+	outputFile := filepath.Join(t.TempDir(), "output.md")
+	if err := main.GenerateMarkdownReport(testSuites, results, outputFile, main.ReportOptions{Nested: true, FailSnippet: 300}); err != nil {
+		t.Fatalf("Failed to generate markdown: %v", err)
+	}
+	content, err := os.ReadFile(outputFile)
+	if err != nil {
+		t.Fatalf("Failed to read output file: %v", err)
+	}
+	output := string(content)
+
+	t.Run("rolled_up_suite_table", func(t *testing.T) {
+		for _, expected := range []string{
+			"| TestParser | ❌ FAIL · 4/6 passed | 0.4s | TestParser checks the parser | raw strings |\n",
+			"| TestLexer | ✅ PASS · 1/1 passed | 0.1s | TestLexer checks the lexer |  |\n| **Total: 9** |",
+		} {
+			if !strings.Contains(output, expected) {
+				t.Errorf("Missing %q in output:\n%s", expected, output)
+			}
+		}
+		if strings.Contains(output, "→ numbers |") || strings.Contains(output, "| TestParser → ") {
+			t.Error("Expected no flattened subtest rows")
+		}
+	})
+
+	t.Run("subtest_blocks", func(t *testing.T) {
+		expected := "<details open>\n<summary><b>TestParser</b>: ❌ FAIL, 4/6 passed</summary>\n\n" +
+			"| Subtest | Status | Duration | Description | Failure |\n|---------|--------|----------|-------------|----------|\n" +
+			"| numbers | ✅ PASS · 2/2 passed | 0.2s |  |  |\n" +
+			"| strings | ❌ FAIL · 1/2 passed | 0.2s |  | raw strings |\n\n" +
+			"<details>\n<summary><b>TestParser → numbers</b>: ✅ PASS, 2/2 passed</summary>\n\n"
+		if !strings.Contains(output, expected) {
+			t.Errorf("Missing %q in output:\n%s", expected, output)
+		}
+		for _, expected := range []string{
+			"<details open>\n<summary><b>TestParser → strings</b>: ❌ FAIL, 1/2 passed</summary>",
+			"| raw | ❌ FAIL | 0.1s |  | unterminated |\n\n</details>\n\n</details>\n\n<details>\n<summary><b>TestLexer</b>",
+		} {
+			if !strings.Contains(output, expected) {
+				t.Errorf("Missing %q in output:\n%s", expected, output)
+			}
+		}
+	})
+}
+
+// writeSampleModule creates a throwaway "testproject" module containing the given files
+func writeSampleModule(t *testing.T, files map[string]string) string {
+	t.Helper()
+	tempDir := t.TempDir()
+	if _, ok := files["go.mod"]; !ok {
+		files["go.mod"] = "module testproject\n\ngo 1.21\n"
+	}
+	for name, content := range files {
+		path := filepath.Join(tempDir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create directory for %s: %v", name, err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to create %s: %v", name, err)
+		}
+	}
+	return tempDir
+}
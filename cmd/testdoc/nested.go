@@ -0,0 +1,74 @@
+package main
+
+import "fmt"
+
+/*** Nested Markdown layout ***/
+
+// subtestBlock is a collapsible block of the nested layout: the table of
+// the subtests of a test and, nested within, the blocks of their own
+// subtests.
+type subtestBlock struct {
+	ReportTest
+	Path     string // like "TestParent → child" as in the Test Path column
+	Rollup   string // like "12/14 passed"
+	Open     bool   // whether the block starts expanded
+	Rows     []templateRow
+	Children []subtestBlock
+}
+
+// nestedRows lists tests as table rows without their subtests. Tests with
+// subtests show how many of them passed after their own status.
+func nestedRows(tests []ReportTest) []templateRow {
+	rows := make([]templateRow, 0, len(tests))
+	for _, test := range tests {
+		row := templateRow{ReportTest: test}
+		if len(test.Subtests) > 0 {
+			row.Rollup = subtestRollup(test)
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+// subtestBlocks returns a block per test with subtests. Blocks of subtrees
+// where every test passed start collapsed.
+func subtestBlocks(tests []ReportTest, pathPrefix string) []subtestBlock {
+	var blocks []subtestBlock
+	for _, test := range tests {
+		if len(test.Subtests) == 0 {
+			continue
+		}
+		path := test.Name
+		if pathPrefix != "" {
+			path = pathPrefix + " → " + test.Name
+		}
+		blocks = append(blocks, subtestBlock{
+			ReportTest: test,
+			Path:       path,
+			Rollup:     subtestRollup(test),
+			Open:       !subtreePassed(test),
+			Rows:       nestedRows(test.Subtests),
+			Children:   subtestBlocks(test.Subtests, path),
+		})
+	}
+	return blocks
+}
+
+// subtestRollup summarizes the results of all subtests of test, at any
+// depth, like "12/14 passed". Flaky subtests count as passed.
+func subtestRollup(test ReportTest) string {
+	var stats Stats
+	stats.add(test.Subtests, "")
+	return fmt.Sprintf("%d/%d passed", stats.Passed+stats.Flaky, stats.Total)
+}
+
+// subtreePassed reports whether test and all its subtests passed on their
+// first attempt.
+func subtreePassed(test ReportTest) bool {
+	if test.Status != "PASS" {
+		return false
+	}
+	var stats Stats
+	stats.add(test.Subtests, "")
+	return stats.Passed == stats.Total
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"sort"
+	"strings"
+)
+
+/*** Reconciling JUnit results with the source tree ***/
+
+// unmatchedSuiteName names the suite holding the dynamic tests of a package
+// that have no static parent to be attached to.
+const unmatchedSuiteName = "JUnit results without source"
+
+// ReconcileResults adds the JUnit records no static test unit matches, such
+// as subtests with names computed at run time, to the test tree as Dynamic
+// units. Each is attached under its closest parent by name prefix, so
+// "TestX/case_42" lands under TestX, and records without any parent are
+// collected in a suite of their own per package.
+func ReconcileResults(testSuites []TestSuite, jmap map[string]junitRecord) []TestSuite {
+	known := make(map[string]bool)
+	var index func(units []TestUnit, pkgName string)
+	index = func(units []TestUnit, pkgName string) {
+		for _, tu := range units {
+			known[unitKey(tu, pkgName)] = true
+			index(tu.Subtests, pkgName)
+		}
+	}
+	for _, ts := range testSuites {
+		index(ts.TestUnits, ts.PackageName)
+	}
+
+	var unmatched []string
+	for key := range jmap {
+		if !known[key] {
+			unmatched = append(unmatched, key)
+		}
+	}
+	if len(unmatched) == 0 {
+		return testSuites
+	}
+	// Parents sort before their subtests
+	sort.Strings(unmatched)
+
+	children := make(map[string][]TestUnit)
+	var orphans []string // packages with records lacking a parent
+	orphanUnits := make(map[string][]TestUnit)
+	for _, key := range unmatched {
+		pkg, name, _ := strings.Cut(key, "::")
+		unit := TestUnit{
+			MachineTestName: name,
+			TestName:        name,
+			ClassName:       pkg,
+			Dynamic:         true,
+		}
+		known[key] = true
+
+		parentKey := ""
+		for i := strings.LastIndex(name, "/"); i > 0; i = strings.LastIndex(name[:i], "/") {
+			if candidate := pkgKey(pkg, name[:i]); known[candidate] {
+				parentKey = candidate
+				unit.TestName = name[i+1:]
+				break
+			}
+		}
+		if parentKey != "" {
+			children[parentKey] = append(children[parentKey], unit)
+			continue
+		}
+		if _, ok := orphanUnits[pkg]; !ok {
+			orphans = append(orphans, pkg)
+		}
+		orphanUnits[pkg] = append(orphanUnits[pkg], unit)
+	}
+
+	var attach func(units []TestUnit, pkgName string) []TestUnit
+	attach = func(units []TestUnit, pkgName string) []TestUnit {
+		out := make([]TestUnit, len(units))
+		for i, tu := range units {
+			tu.Subtests = attach(append(append([]TestUnit{}, tu.Subtests...), children[unitKey(tu, pkgName)]...), pkgName)
+			out[i] = tu
+		}
+		return out
+	}
+
+	// Orphans follow the other suites of their package, if it has any
+	reconciled := make([]TestSuite, 0, len(testSuites)+len(orphans))
+	addOrphans := func(pkg string) {
+		if units, ok := orphanUnits[pkg]; ok {
+			reconciled = append(reconciled, TestSuite{
+				PackageName: pkg,
+				Name:        unmatchedSuiteName,
+				TestUnits:   attach(units, pkg),
+			})
+			delete(orphanUnits, pkg)
+		}
+	}
+	for i, ts := range testSuites {
+		ts.TestUnits = attach(ts.TestUnits, ts.PackageName)
+		reconciled = append(reconciled, ts)
+		if i == len(testSuites)-1 || testSuites[i+1].PackageName != ts.PackageName {
+			addOrphans(ts.PackageName)
+		}
+	}
+	sort.Strings(orphans)
+	for _, pkg := range orphans {
+		addOrphans(pkg)
+	}
+	return reconciled
+}
+
+// unitKey is the JUnit key of a test in package pkgName.
+func unitKey(tu TestUnit, pkgName string) string {
+	if tu.ClassName != "" {
+		return pkgKey(tu.ClassName, tu.MachineTestName)
+	}
+	return pkgKey(pkgName, tu.MachineTestName)
+}
+
+// countDynamic returns the number of dynamic units in the suites per JUnit
+// package (or classname), and those packages in sorted order.
+func countDynamic(testSuites []TestSuite) (map[string]int, []string) {
+	counts := make(map[string]int)
+	var count func(units []TestUnit, pkgName string)
+	count = func(units []TestUnit, pkgName string) {
+		for _, tu := range units {
+			if tu.Dynamic {
+				pkg, _, _ := strings.Cut(unitKey(tu, pkgName), "::")
+				counts[pkg]++
+			}
+			count(tu.Subtests, pkgName)
+		}
+	}
+	for _, ts := range testSuites {
+		count(ts.TestUnits, ts.PackageName)
+	}
+
+	pkgs := make([]string, 0, len(counts))
+	for pkg := range counts {
+		pkgs = append(pkgs, pkg)
+	}
+	sort.Strings(pkgs)
+	return counts, pkgs
+}
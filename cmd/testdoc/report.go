@@ -0,0 +1,136 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+/*** Report model and JSON output ***/
+
+// ReportSchemaVersion is the version of the JSON report layout, described by
+// schema/report.schema.json. It changes only when fields are renamed or
+// removed, or their meaning changes; new optional fields keep the version.
+const ReportSchemaVersion = 1
+
+// Report is the merged view of the source test tree and the test results
+// that every output format renders.
+type Report struct {
+	SchemaVersion int           `json:"schemaVersion"`
+	Suites        []ReportSuite `json:"suites"`
+}
+
+// ReportSuite is a test file of a package.
+type ReportSuite struct {
+	Package     string       `json:"package"`
+	PackageDoc  string       `json:"packageDoc,omitempty"`
+	Name        string       `json:"name"`
+	Description string       `json:"description,omitempty"`
+	Tests       []ReportTest `json:"tests"`
+}
+
+// ReportTest is a test, subtest, benchmark, fuzz seed or example with its
+// result. Status is NOT RUN for tests without a result.
+type ReportTest struct {
+	Name          string              `json:"name"`
+	MachineName   string              `json:"machineName"`
+	ClassName     string              `json:"className"`
+	Kind          string              `json:"kind"`
+	Summary       string              `json:"summary,omitempty"`
+	Comment       string              `json:"comment,omitempty"`
+	Tags          map[string][]string `json:"tags,omitempty"`
+	File          string              `json:"file,omitempty"`
+	Line          int                 `json:"line,omitempty"`
+	Dynamic       bool                `json:"dynamic,omitempty"`
+	Container     bool                `json:"container,omitempty"`
+	ExampleOutput string              `json:"exampleOutput,omitempty"`
+	Status        string              `json:"status"`
+	Duration      string              `json:"duration,omitempty"`
+	Seconds       float64             `json:"seconds,omitempty"`
+	Attempts      int                 `json:"attempts,omitempty"`
+	Failure       string              `json:"failure,omitempty"`
+	Details       string              `json:"details,omitempty"`
+	Stdout        string              `json:"stdout,omitempty"`
+	Stderr        string              `json:"stderr,omitempty"`
+	Conflicts     []string            `json:"conflicts,omitempty"`
+	Subtests      []ReportTest        `json:"subtests"`
+
+	unit TestUnit // the source test, for Stats.Slowest
+}
+
+// BuildReport attaches the result of each test in jmap to the test tree.
+func BuildReport(testSuites []TestSuite, jmap map[string]junitRecord) Report {
+	report := Report{
+		SchemaVersion: ReportSchemaVersion,
+		Suites:        make([]ReportSuite, 0, len(testSuites)),
+	}
+	for _, ts := range testSuites {
+		suite := ReportSuite{
+			Package:     ts.PackageName,
+			PackageDoc:  ts.PackageDoc,
+			Name:        ts.Name,
+			Description: ts.CommentHeader,
+			Tests:       reportTests(ts.TestUnits, ts.PackageName, KindTest, jmap),
+		}
+		report.Suites = append(report.Suites, suite)
+	}
+	return report
+}
+
+// reportTests converts units to report tests. Units without a kind of their
+// own, such as the subtests of a benchmark, get parentKind.
+func reportTests(units []TestUnit, pkgName, parentKind string, jmap map[string]junitRecord) []ReportTest {
+	tests := make([]ReportTest, 0, len(units))
+	for _, tu := range units {
+		kind := tu.Kind
+		if kind == "" {
+			kind = parentKind
+		}
+		className := tu.ClassName
+		if className == "" {
+			className = pkgName
+		}
+		test := ReportTest{
+			Name:          tu.TestName,
+			MachineName:   tu.MachineTestName,
+			ClassName:     className,
+			Kind:          kind,
+			Summary:       extractSummaryFromComment(tu.CommentHeader),
+			Comment:       tu.CommentHeader,
+			Tags:          tu.Tags,
+			File:          tu.File,
+			Line:          tu.Line,
+			Dynamic:       tu.Dynamic,
+			Container:     tu.Container,
+			ExampleOutput: tu.ExampleOutput,
+			Status:        "NOT RUN",
+			Subtests:      reportTests(tu.Subtests, pkgName, kind, jmap),
+			unit:          tu,
+		}
+		if rec, ok := lookupRecord(tu, pkgName, jmap); ok {
+			test.Status = rec.Status
+			test.Duration = rec.Duration
+			test.Seconds = rec.Seconds
+			test.Attempts = rec.Attempts
+			test.Failure = rec.Failure
+			test.Details = rec.Details
+			test.Stdout = rec.Stdout
+			test.Stderr = rec.Stderr
+			test.Conflicts = rec.Conflicts
+		}
+		tests = append(tests, test)
+	}
+	return tests
+}
+
+// GenerateJSONReport writes the report model as indented JSON.
+func GenerateJSONReport(testSuites []TestSuite, jmap map[string]junitRecord, outPath string) error {
+	b, err := json.MarshalIndent(BuildReport(testSuites, jmap), "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding JSON report: %v", err)
+	}
+	if err := os.WriteFile(outPath, append(b, '\n'), 0644); err != nil {
+		return fmt.Errorf("error creating output file: %v", err)
+	}
+	return nil
+}
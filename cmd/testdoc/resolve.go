@@ -0,0 +1,560 @@
+package main
+
+import (
+	"go/ast"
+	"go/constant"
+	"go/token"
+	"go/types"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+)
+
+/*** Name and declaration resolution ***/
+
+// sourcePackage is the type-checked view of a test package that subtest
+// names, case tables and constants are resolved against, along with the
+// subtest names already handed out in it. A nil *sourcePackage is valid and
+// falls back to purely syntactic resolution.
+type sourcePackage struct {
+	root     string // directory test file positions are reported relative to
+	fset     *token.FileSet
+	files    []*ast.File
+	typesPkg *types.Package
+	info     *types.Info
+	names    *subtestNames
+
+	// While descending into helper functions: the arguments their
+	// parameters are bound to, and the helpers currently being collected.
+	params map[types.Object]ast.Expr
+	active map[*ast.FuncDecl]bool
+
+	// Variables assigned to after their declaration, found on first use.
+	reassigned map[types.Object]bool
+}
+
+func newSourcePackage(p *packages.Package, root string) *sourcePackage {
+	return &sourcePackage{
+		root:     root,
+		fset:     p.Fset,
+		files:    p.Syntax,
+		typesPkg: p.Types,
+		info:     p.TypesInfo,
+		names:    newSubtestNames(),
+		params:   make(map[types.Object]ast.Expr),
+		active:   make(map[*ast.FuncDecl]bool),
+	}
+}
+
+// fileAt returns the file of the package containing pos.
+func (sp *sourcePackage) fileAt(pos token.Pos) *ast.File {
+	for _, f := range sp.files {
+		if f.FileStart <= pos && pos <= f.FileEnd {
+			return f
+		}
+	}
+	return nil
+}
+
+// position returns the file, relative to the scanned directory, and line of pos.
+func (sp *sourcePackage) position(pos token.Pos) (string, int) {
+	if sp == nil || sp.fset == nil || !pos.IsValid() {
+		return "", 0
+	}
+	position := sp.fset.Position(pos)
+	file := position.Filename
+	if rel, err := filepath.Rel(sp.root, file); err == nil && !strings.HasPrefix(rel, "..") {
+		file = rel
+	}
+	return filepath.ToSlash(file), position.Line
+}
+
+// commentAbove finds the comment directly above pos like FindRelativeComment,
+// looking in whichever file of the package pos is in rather than the current one.
+func (sp *sourcePackage) commentAbove(pos token.Pos, comments []*ast.CommentGroup, file *token.File, filePath string) string {
+	if sp != nil && sp.fset != nil && (pos < token.Pos(file.Base()) || pos > token.Pos(file.Base()+file.Size())) {
+		if astFile := sp.fileAt(pos); astFile != nil {
+			file = sp.fset.File(pos)
+			comments, filePath = astFile.Comments, file.Name()
+		}
+	}
+	return FindRelativeComment(pos, comments, file, filePath)
+}
+
+// funcDecl resolves a function identifier or method value like s.testX to
+// its declaration in the package, or returns nil.
+func (sp *sourcePackage) funcDecl(expr ast.Expr) *ast.FuncDecl {
+	var ident *ast.Ident
+	switch e := expr.(type) {
+	case *ast.Ident:
+		ident = e
+	case *ast.SelectorExpr:
+		ident = e.Sel
+	case *ast.ParenExpr:
+		return sp.funcDecl(e.X)
+	default:
+		return nil
+	}
+
+	if sp == nil || sp.info == nil {
+		if ident.Obj != nil {
+			if fd, ok := ident.Obj.Decl.(*ast.FuncDecl); ok && fd.Body != nil {
+				return fd
+			}
+		}
+		return nil
+	}
+
+	fn, ok := sp.info.Uses[ident].(*types.Func)
+	if !ok {
+		return nil
+	}
+	return sp.declOf(fn)
+}
+
+// declOf returns the declaration of a function or method of the package.
+func (sp *sourcePackage) declOf(fn *types.Func) *ast.FuncDecl {
+	if fn.Pkg() != sp.typesPkg {
+		return nil
+	}
+	f := sp.fileAt(fn.Pos())
+	if f == nil {
+		return nil
+	}
+	for _, decl := range f.Decls {
+		if fd, ok := decl.(*ast.FuncDecl); ok && fd.Name.Pos() == fn.Pos() && fd.Body != nil {
+			return fd
+		}
+	}
+	return nil
+}
+
+// collectFromDecl collects the subtests started inside a named function of
+// the package, with its parameters bound to args when called as a helper.
+// Functions already being collected are skipped to break recursion cycles.
+func (sp *sourcePackage) collectFromDecl(fd *ast.FuncDecl, args []ast.Expr, parentName string, expandedVariables []ExpandedVar) []TestUnit {
+	if sp == nil || sp.active[fd] {
+		return nil
+	}
+	astFile := sp.fileAt(fd.Pos())
+	if astFile == nil {
+		return nil
+	}
+
+	sp.active[fd] = true
+	defer delete(sp.active, fd)
+
+	if sp.info != nil && args != nil {
+		var bound []types.Object
+		i := 0
+		for _, field := range fd.Type.Params.List {
+			for _, name := range field.Names {
+				if _, variadic := field.Type.(*ast.Ellipsis); !variadic && i < len(args) {
+					if obj := sp.info.Defs[name]; obj != nil {
+						sp.params[obj] = args[i]
+						bound = append(bound, obj)
+					}
+				}
+				i++
+			}
+			if len(field.Names) == 0 {
+				i++
+			}
+		}
+		defer func() {
+			for _, obj := range bound {
+				delete(sp.params, obj)
+			}
+		}()
+	}
+
+	file := sp.fset.File(fd.Pos())
+	return CollectSubtests(sp, fd.Body, astFile.Comments, file, file.Name(), parentName, expandedVariables)
+}
+
+// constString evaluates expr as a compile-time constant and formats it the
+// way fmt would print it, e.g. for `const name = "x"` or `caseA Mode = "a"`.
+func (sp *sourcePackage) constString(expr ast.Expr) (string, bool) {
+	if sp == nil || sp.info == nil {
+		return "", false
+	}
+	tv, ok := sp.info.Types[expr]
+	if !ok || tv.Value == nil {
+		return "", false
+	}
+	switch tv.Value.Kind() {
+	case constant.String:
+		return constant.StringVal(tv.Value), true
+	case constant.Float:
+		f, _ := constant.Float64Val(tv.Value)
+		return strconv.FormatFloat(f, 'g', -1, 64), true
+	case constant.Unknown:
+		return "", false
+	default:
+		return tv.Value.ExactString(), true
+	}
+}
+
+// isConversion reports whether call is a type conversion such as string(m).
+// Without type information only conversions to string are recognised.
+func (sp *sourcePackage) isConversion(call *ast.CallExpr) bool {
+	if sp != nil && sp.info != nil {
+		if tv, ok := sp.info.Types[call.Fun]; ok {
+			return tv.IsType()
+		}
+	}
+	ident, ok := call.Fun.(*ast.Ident)
+	return ok && ident.Name == "string"
+}
+
+// calleeName names the function a call invokes as "pkg.Func", using the
+// import path from type information or the qualifier as written otherwise.
+func (sp *sourcePackage) calleeName(call *ast.CallExpr) string {
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok {
+		return ""
+	}
+	if sp != nil && sp.info != nil {
+		if fn, ok := sp.info.Uses[sel.Sel].(*types.Func); ok {
+			if fn.Pkg() == nil || fn.Signature().Recv() != nil {
+				return ""
+			}
+			return fn.Pkg().Path() + "." + fn.Name()
+		}
+	}
+	if x, ok := sel.X.(*ast.Ident); ok {
+		return x.Name + "." + sel.Sel.Name
+	}
+	return ""
+}
+
+// basicInfo returns the basic type properties of expr, or 0 if unknown.
+func (sp *sourcePackage) basicInfo(expr ast.Expr) types.BasicInfo {
+	if sp == nil || sp.info == nil {
+		return 0
+	}
+	if t := sp.info.TypeOf(expr); t != nil {
+		if basic, ok := t.Underlying().(*types.Basic); ok {
+			return basic.Info()
+		}
+	}
+	return 0
+}
+
+// formatsAsValue reports whether fmt prints expr as its underlying value:
+// its type is a basic type without a String, Error or Format method, which
+// fmt would call instead. Without type information it is assumed to.
+func (sp *sourcePackage) formatsAsValue(expr ast.Expr) bool {
+	if sp == nil || sp.info == nil {
+		return true
+	}
+	t := sp.info.TypeOf(expr)
+	if t == nil {
+		return true
+	}
+	if _, ok := t.Underlying().(*types.Basic); !ok {
+		return false
+	}
+	for _, name := range []string{"String", "Error", "Format"} {
+		if obj, _, _ := types.LookupFieldOrMethod(t, false, sp.typesPkg, name); obj != nil {
+			if _, ok := obj.(*types.Func); ok {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// resolveCompositeLit follows an identifier back to the composite literal it
+// was declared with, either earlier in the function or at package level.
+func (sp *sourcePackage) resolveCompositeLit(expr ast.Expr) *ast.CompositeLit {
+	switch e := expr.(type) {
+	case *ast.CompositeLit:
+		return e
+	case *ast.ParenExpr:
+		return sp.resolveCompositeLit(e.X)
+	case *ast.Ident:
+		if value := sp.declaredValue(e); value != nil {
+			return sp.resolveCompositeLit(value)
+		}
+	}
+	return nil
+}
+
+// declaredValue returns the initializer an identifier was declared with,
+// or nil if it was declared without one or, with type information, is
+// assigned to again, as its value at the use is then unknown. Declarations
+// in other files of the package are only found when type information is
+// available.
+func (sp *sourcePackage) declaredValue(ident *ast.Ident) ast.Expr {
+	if sp != nil && sp.info != nil {
+		if obj := sp.info.Uses[ident]; obj != nil {
+			if arg, ok := sp.params[obj]; ok {
+				return arg
+			}
+			if _, ok := obj.(*types.Var); ok {
+				if sp.isReassigned(obj) {
+					return nil
+				}
+				return sp.initializerAt(obj.Pos())
+			}
+			return nil
+		}
+	}
+
+	if ident.Obj == nil {
+		return nil
+	}
+	switch decl := ident.Obj.Decl.(type) {
+	case *ast.AssignStmt:
+		return initializerFor(decl.Lhs, decl.Rhs, ident.Name)
+	case *ast.ValueSpec:
+		names := make([]ast.Expr, len(decl.Names))
+		for i, name := range decl.Names {
+			names[i] = name
+		}
+		return initializerFor(names, decl.Values, ident.Name)
+	}
+	return nil
+}
+
+// isReassigned reports whether the variable obj is assigned to anywhere
+// after its declaration, or has its address taken, so that its initializer
+// need not be its value where it is used.
+func (sp *sourcePackage) isReassigned(obj types.Object) bool {
+	if sp.reassigned == nil {
+		sp.reassigned = make(map[types.Object]bool)
+		mark := func(expr ast.Expr) {
+			if ident, ok := ast.Unparen(expr).(*ast.Ident); ok {
+				if v := sp.info.Uses[ident]; v != nil {
+					sp.reassigned[v] = true
+				}
+			}
+		}
+		for _, f := range sp.files {
+			ast.Inspect(f, func(n ast.Node) bool {
+				switch n := n.(type) {
+				case *ast.AssignStmt:
+					// Names redeclared by := are uses, not definitions
+					for _, lhs := range n.Lhs {
+						mark(lhs)
+					}
+				case *ast.IncDecStmt:
+					mark(n.X)
+				case *ast.RangeStmt:
+					if n.Tok == token.ASSIGN {
+						mark(n.Key)
+						mark(n.Value)
+					}
+				case *ast.UnaryExpr:
+					if n.Op == token.AND {
+						mark(n.X)
+					}
+				}
+				return true
+			})
+		}
+	}
+	return sp.reassigned[obj]
+}
+
+// initializerAt finds the declaration whose name is at pos and returns the
+// expression assigned to that name.
+func (sp *sourcePackage) initializerAt(pos token.Pos) ast.Expr {
+	for _, f := range sp.files {
+		if pos < f.FileStart || pos > f.FileEnd {
+			continue
+		}
+		var value ast.Expr
+		ast.Inspect(f, func(n ast.Node) bool {
+			if value != nil || n == nil || pos < n.Pos() || pos > n.End() {
+				return false
+			}
+			switch decl := n.(type) {
+			case *ast.AssignStmt:
+				if decl.Tok == token.DEFINE {
+					value = initializerAtPos(decl.Lhs, decl.Rhs, pos)
+				}
+			case *ast.ValueSpec:
+				names := make([]ast.Expr, len(decl.Names))
+				for i, name := range decl.Names {
+					names[i] = name
+				}
+				value = initializerAtPos(names, decl.Values, pos)
+			}
+			return value == nil
+		})
+		return value
+	}
+	return nil
+}
+
+func initializerFor(lhs, rhs []ast.Expr, name string) ast.Expr {
+	if len(lhs) != len(rhs) {
+		return nil
+	}
+	for i, l := range lhs {
+		if id, ok := l.(*ast.Ident); ok && id.Name == name {
+			return rhs[i]
+		}
+	}
+	return nil
+}
+
+func initializerAtPos(lhs, rhs []ast.Expr, pos token.Pos) ast.Expr {
+	if len(lhs) != len(rhs) {
+		return nil
+	}
+	for i, l := range lhs {
+		if id, ok := l.(*ast.Ident); ok && id.Pos() == pos {
+			return rhs[i]
+		}
+	}
+	return nil
+}
+
+// tableFieldNames lists the field names of a table entry's struct literal in
+// declaration order, preferring the type checker's view of the struct.
+func (sp *sourcePackage) tableFieldNames(lit *ast.CompositeLit, elemType ast.Expr) []string {
+	if sp != nil && sp.info != nil {
+		if t := sp.info.TypeOf(lit); t != nil {
+			if st, ok := t.Underlying().(*types.Struct); ok {
+				names := make([]string, st.NumFields())
+				for i := range names {
+					names[i] = st.Field(i).Name()
+				}
+				return names
+			}
+		}
+	}
+	if lit.Type != nil {
+		return structFieldNames(lit.Type)
+	}
+	return structFieldNames(elemType)
+}
+
+// tableFieldZeros returns, by field name, the zero value of each field of a
+// table entry's struct type whose zero value formats the same as a literal,
+// that is fields of string, boolean and non-complex numeric types.
+func (sp *sourcePackage) tableFieldZeros(lit *ast.CompositeLit, elemType ast.Expr) map[string]ast.Expr {
+	zeros := make(map[string]ast.Expr)
+	if sp != nil && sp.info != nil {
+		if t := sp.info.TypeOf(lit); t != nil {
+			if st, ok := t.Underlying().(*types.Struct); ok {
+				for i := 0; i < st.NumFields(); i++ {
+					basic, ok := st.Field(i).Type().Underlying().(*types.Basic)
+					if !ok {
+						continue
+					}
+					if zero := zeroLiteral(basic.Info()); zero != nil {
+						zeros[st.Field(i).Name()] = zero
+					}
+				}
+				return zeros
+			}
+		}
+	}
+
+	typ := elemType
+	if lit.Type != nil {
+		typ = lit.Type
+	}
+	st := structType(typ)
+	if st == nil {
+		return zeros
+	}
+	for _, field := range st.Fields.List {
+		ident, ok := field.Type.(*ast.Ident)
+		if !ok {
+			continue
+		}
+		basic, ok := types.Universe.Lookup(ident.Name).(*types.TypeName)
+		if !ok {
+			continue
+		}
+		b, ok := basic.Type().(*types.Basic)
+		if !ok {
+			continue
+		}
+		if zero := zeroLiteral(b.Info()); zero != nil {
+			for _, name := range field.Names {
+				zeros[name.Name] = zero
+			}
+		}
+	}
+	return zeros
+}
+
+// zeroLiteral is an expression formatting as the zero value of a basic type
+// with the given info, or nil if there is none.
+func zeroLiteral(info types.BasicInfo) ast.Expr {
+	switch {
+	case info&types.IsString != 0:
+		return &ast.BasicLit{Kind: token.STRING, Value: `""`}
+	case info&types.IsBoolean != 0:
+		return ast.NewIdent("false")
+	case info&(types.IsInteger|types.IsFloat) != 0:
+		return &ast.BasicLit{Kind: token.INT, Value: "0"}
+	}
+	return nil
+}
+
+// structType resolves a struct type expression, following pointers and
+// named types declared in the file.
+func structType(expr ast.Expr) *ast.StructType {
+	switch e := expr.(type) {
+	case *ast.StarExpr:
+		return structType(e.X)
+	case *ast.Ident:
+		if e.Obj != nil {
+			if spec, ok := e.Obj.Decl.(*ast.TypeSpec); ok {
+				return structType(spec.Type)
+			}
+		}
+	case *ast.StructType:
+		return e
+	}
+	return nil
+}
+
+// structFieldNames lists the field names of a struct type expression in
+// declaration order, following pointers and named types declared in the file.
+func structFieldNames(expr ast.Expr) []string {
+	switch e := expr.(type) {
+	case *ast.StarExpr:
+		return structFieldNames(e.X)
+	case *ast.Ident:
+		if e.Obj != nil {
+			if spec, ok := e.Obj.Decl.(*ast.TypeSpec); ok {
+				return structFieldNames(spec.Type)
+			}
+		}
+	case *ast.StructType:
+		var names []string
+		for _, field := range e.Fields.List {
+			if len(field.Names) == 0 {
+				// Embedded field: named after its type
+				typ := field.Type
+				if star, ok := typ.(*ast.StarExpr); ok {
+					typ = star.X
+				}
+				switch t := typ.(type) {
+				case *ast.Ident:
+					names = append(names, t.Name)
+				case *ast.SelectorExpr:
+					names = append(names, t.Sel.Name)
+				default:
+					names = append(names, "")
+				}
+				continue
+			}
+			for _, name := range field.Names {
+				names = append(names, name.Name)
+			}
+		}
+		return names
+	}
+	return nil
+}
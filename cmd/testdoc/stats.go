@@ -0,0 +1,159 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+)
+
+/*** Summary statistics ***/
+
+// Stats are the totals of a set of tests and their subtests.
+type Stats struct {
+	Total   int
+	Passed  int
+	Failed  int // FAIL and ERROR
+	Flaky   int
+	Skipped int
+	NotRun  int // tests without a result
+	// Undocumented counts the tests found in source without a comment.
+	Undocumented int
+	// Seconds is the duration of the top-level tests, which includes that
+	// of their subtests.
+	Seconds float64
+	// Slowest are the tests, subtests included, that took longest, slowest
+	// first.
+	Slowest []SlowTest
+}
+
+// SlowTest is a test of Stats.Slowest.
+type SlowTest struct {
+	Path    string // "TestParent → child" as in the report's Test Path column
+	Unit    TestUnit
+	Seconds float64
+}
+
+// PassRate is the percentage of the tests that ran, neither skipped nor
+// without a result, that passed, flaky tests included. It is 0 when no test
+// ran.
+func (s Stats) PassRate() float64 {
+	run := s.Passed + s.Failed + s.Flaky
+	if run == 0 {
+		return 0
+	}
+	return float64(s.Passed+s.Flaky) * 100 / float64(run)
+}
+
+// ComputeStats totals the results in jmap over the test tree, keeping the
+// slowest tests.
+func ComputeStats(testSuites []TestSuite, jmap map[string]junitRecord, slowest int) Stats {
+	var stats Stats
+	var timed []SlowTest
+	for _, ts := range testSuites {
+		timed = append(timed, stats.add(reportTests(ts.TestUnits, ts.PackageName, KindTest, jmap), "")...)
+	}
+
+	sort.SliceStable(timed, func(i, j int) bool { return timed[i].Seconds > timed[j].Seconds })
+	if len(timed) > slowest {
+		timed = timed[:max(slowest, 0)]
+	}
+	stats.Slowest = timed
+	return stats
+}
+
+// testStats totals tests and their subtests, like the totals row of a
+// suite table.
+func testStats(tests []ReportTest) Stats {
+	var stats Stats
+	stats.add(tests, "")
+	return stats
+}
+
+// add counts tests and their subtests into s, returning those with a
+// duration. Containers are not tests themselves; only their subtests count.
+func (s *Stats) add(tests []ReportTest, pathPrefix string) []SlowTest {
+	var timed []SlowTest
+	for _, test := range tests {
+		path := test.Name
+		if pathPrefix != "" {
+			path = pathPrefix + " → " + test.Name
+		}
+		if test.Container {
+			if pathPrefix == "" {
+				s.Seconds += test.Seconds
+			}
+			timed = append(timed, s.add(test.Subtests, path)...)
+			continue
+		}
+
+		s.Total++
+		if test.Comment == "" && !test.Dynamic {
+			s.Undocumented++
+		}
+		switch test.Status {
+		case "NOT RUN":
+			s.NotRun++
+		case "PASS":
+			s.Passed++
+		case "FLAKY":
+			s.Flaky++
+		case "SKIP":
+			s.Skipped++
+		default:
+			s.Failed++
+		}
+		if test.Seconds > 0 {
+			if pathPrefix == "" {
+				s.Seconds += test.Seconds
+			}
+			timed = append(timed, SlowTest{Path: path, Unit: test.unit, Seconds: test.Seconds})
+		}
+
+		timed = append(timed, s.add(test.Subtests, path)...)
+	}
+	return timed
+}
+
+// statusCounts returns the non-zero counts of s by status, FAIL standing
+// for FAIL and ERROR.
+func (s Stats) statusCounts() []statusCount {
+	var counts []statusCount
+	for _, c := range []statusCount{{"PASS", s.Passed}, {"FAIL", s.Failed}, {"FLAKY", s.Flaky}, {"SKIP", s.Skipped}, {"NOT RUN", s.NotRun}} {
+		if c.Count > 0 {
+			counts = append(counts, c)
+		}
+	}
+	return counts
+}
+
+// WriteGitHubOutputs appends the summary statistics to a GitHub Actions
+// step output file, as named by $GITHUB_OUTPUT.
+func WriteGitHubOutputs(path string, stats Stats) error {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("error opening GitHub output file: %v", err)
+	}
+	defer f.Close()
+
+	outputs := []struct {
+		name, value string
+	}{
+		{"total", strconv.Itoa(stats.Total)},
+		{"passed", strconv.Itoa(stats.Passed)},
+		{"failed", strconv.Itoa(stats.Failed)},
+		{"flaky", strconv.Itoa(stats.Flaky)},
+		{"skipped", strconv.Itoa(stats.Skipped)},
+		{"not_run", strconv.Itoa(stats.NotRun)},
+		{"pass_rate", strconv.FormatFloat(stats.PassRate(), 'f', 1, 64)},
+		{"duration_seconds", strconv.FormatFloat(math.Round(stats.Seconds*1000)/1000, 'f', -1, 64)},
+		{"undocumented", strconv.Itoa(stats.Undocumented)},
+	}
+	for _, out := range outputs {
+		if _, err := fmt.Fprintf(f, "%s=%s\n", out.name, out.value); err != nil {
+			return fmt.Errorf("error writing GitHub output file: %v", err)
+		}
+	}
+	return f.Close()
+}
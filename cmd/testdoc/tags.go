@@ -0,0 +1,60 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+)
+
+/*** Structured comment tags ***/
+
+// knownTags are the tags rendered as badges in the report, in order.
+// Any other tag is kept on the TestUnit but not rendered.
+var knownTags = []string{"owner", "requirement", "tag", "issue", "severity"}
+
+// tagLine matches "@key: value" lines; the colon or the space after it may
+// be omitted, as in "@key value" or "@key:value".
+var tagLine = regexp.MustCompile(`^@([A-Za-z][\w-]*)(?::\s*|\s+|$)(.*)$`)
+
+// ParseCommentTags collects the "@key: value" lines of a test comment, such
+// as "@owner: payments-team" or "@requirement: REQ-101". Keys are lower-cased,
+// and a key repeated on several lines keeps every value in order. It returns
+// nil if the comment has no tags.
+func ParseCommentTags(comment string) map[string][]string {
+	var tags map[string][]string
+	for _, line := range strings.Split(comment, "\n") {
+		m := tagLine.FindStringSubmatch(strings.TrimSpace(line))
+		if m == nil {
+			continue
+		}
+		if tags == nil {
+			tags = make(map[string][]string)
+		}
+		key := strings.ToLower(m[1])
+		tags[key] = append(tags[key], strings.TrimSpace(m[2]))
+	}
+	return tags
+}
+
+// tagUnits fills in the tags of each unit and its subtests from their comments.
+func tagUnits(units []TestUnit) {
+	for i := range units {
+		units[i].Tags = ParseCommentTags(units[i].CommentHeader)
+		tagUnits(units[i].Subtests)
+	}
+}
+
+// tagLabels returns the known tags of a unit as "key: value" labels, or just
+// "key" for tags without a value, in knownTags order.
+func tagLabels(tags map[string][]string) []string {
+	var labels []string
+	for _, key := range knownTags {
+		for _, value := range tags[key] {
+			label := key
+			if value != "" {
+				label += ": " + value
+			}
+			labels = append(labels, label)
+		}
+	}
+	return labels
+}
@@ -0,0 +1,256 @@
+package main
+
+import (
+	_ "embed"
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+)
+
+/*** User-supplied report templates ***/
+
+//go:embed templates/markdown.tmpl
+var defaultReportTemplate string
+
+// defaultTemplateName selects the built-in template, the layout of the
+// Markdown report of GenerateMarkdownReport.
+const defaultTemplateName = "default"
+
+// reportStatuses are the statuses of the report model, in the order the
+// HTML report offers them as filters.
+var reportStatuses = []string{"FAIL", "ERROR", "FLAKY", "PASS", "SKIP", "NOT RUN"}
+
+// statusCount is the number of tests of the report with a status.
+type statusCount struct {
+	Status string
+	Count  int
+}
+
+// templateData is what report templates render: the report model, with
+// the number of tests, subtests included, in total and per status, the
+// summary statistics and the report options.
+type templateData struct {
+	Report
+	Total   int
+	Counts  []statusCount // statuses with at least one test, in reportStatuses order
+	Stats   Stats
+	Options ReportOptions
+
+	testSuites []TestSuite
+	jmap       map[string]junitRecord
+}
+
+func buildTemplateData(testSuites []TestSuite, jmap map[string]junitRecord, opts ReportOptions) templateData {
+	data := templateData{
+		Report:     BuildReport(testSuites, jmap),
+		Stats:      ComputeStats(testSuites, jmap, opts.Slowest),
+		Options:    opts,
+		testSuites: testSuites,
+		jmap:       jmap,
+	}
+	counts := make(map[string]int)
+	var count func(tests []ReportTest)
+	count = func(tests []ReportTest) {
+		for _, test := range tests {
+			counts[test.Status]++
+			data.Total++
+			count(test.Subtests)
+		}
+	}
+	for _, suite := range data.Suites {
+		count(suite.Tests)
+	}
+	for _, status := range reportStatuses {
+		if counts[status] > 0 {
+			data.Counts = append(data.Counts, statusCount{Status: status, Count: counts[status]})
+		}
+	}
+	return data
+}
+
+// templateRow is a test of a flattened test tree, with the path of its
+// parents as in the Test Path column, like "TestParent → ". In the nested
+// layout, Rollup is the subtest summary of tests with subtests.
+type templateRow struct {
+	ReportTest
+	Prefix string
+	Depth  int
+	Rollup string
+}
+
+// templateGroup is the tests of a suite of one kind.
+type templateGroup struct {
+	Kind  string
+	Title string // "Tests", "Benchmarks", "Fuzz Tests" or "Examples"
+	Tests []ReportTest
+}
+
+// templateFuncs are the functions available to report templates, both
+// text/template and html/template ones.
+func templateFuncs(data templateData) map[string]interface{} {
+	opts := data.Options
+	return map[string]interface{}{
+		"icon":           getStatusIcon,
+		"failed":         isFailure,
+		"tags":           tagLabels,
+		"truncate":       func(n int, s string) string { return truncate(s, n) },
+		"escapeMarkdown": escapeMarkdown,
+		"cell":           escapeCell,
+		"linkText":       escapeLinkText,
+		"fence":          fencedText,
+		"indent":         func(prefix, s string) string { return indent(s, prefix) },
+		"join":           strings.Join,
+		"inc":            func(i int) int { return i + 1 },
+		"duration":       formatDuration,
+		"flatten":        flattenTests,
+		"byKind":         groupByKind,
+		"docSummary":     docSummary,
+		"trim":           strings.TrimSpace,
+		"trimNewlines":   func(s string) string { return strings.TrimRight(s, "\n") },
+		"renderDoc":      renderDocComment,
+		// link is the source link of a test, a flattened row or a slow test
+		"link": func(test interface{}) string {
+			switch test := test.(type) {
+			case ReportTest:
+				return sourceLink(TestUnit{File: test.File, Line: test.Line}, opts)
+			case templateRow:
+				return sourceLink(TestUnit{File: test.File, Line: test.Line}, opts)
+			case SlowTest:
+				return sourceLink(test.Unit, opts)
+			}
+			return ""
+		},
+		// snippet limits failure details and output to -fail-snippet characters
+		"snippet": func(s string) string {
+			return truncate(strings.TrimSpace(s), opts.FailSnippet)
+		},
+		// statusClass is the CSS class of a status, e.g. "not-run"
+		"statusClass": func(status string) string {
+			return strings.ToLower(strings.ReplaceAll(status, " ", "-"))
+		},
+		// searchText is the lower-cased text a test is found by
+		"searchText": func(test ReportTest) string {
+			return strings.ToLower(strings.Join([]string{test.Name, test.MachineName, test.Comment}, " "))
+		},
+
+		// Sections of the Markdown report
+		"totals":        testStats,
+		"statusCounts":  Stats.statusCounts,
+		"nestedRows":    nestedRows,
+		"subtestBlocks": func(tests []ReportTest) []subtestBlock { return subtestBlocks(tests, "") },
+		"failures":      func(tests []ReportTest) []failedTest { return failedTests(tests, opts) },
+		"docDetails":    detailedDocs,
+		"flakyTests":    func() []flakyTest { return flakyTests(data.jmap) },
+		"conflicts":     func() []conflictingTest { return conflictingTests(data.jmap) },
+		"unmatched":     func() []unmatchedPackage { return unmatchedPackages(data.testSuites) },
+	}
+}
+
+// GenerateTemplateReport renders the report model with a user-supplied
+// template file, or the built-in Markdown template if templatePath is "" or
+// "default". Templates ending in .html or .htm (optionally followed by
+// .tmpl) are parsed with html/template so their output is escaped; any
+// other is a text/template.
+func GenerateTemplateReport(testSuites []TestSuite, jmap map[string]junitRecord, outPath, templatePath string, opts ReportOptions) error {
+	if abs, err := filepath.Abs(outPath); err == nil {
+		opts.reportDir = filepath.Dir(abs)
+	}
+
+	name, text := "markdown.tmpl", defaultReportTemplate
+	if templatePath != "" && templatePath != defaultTemplateName {
+		b, err := os.ReadFile(templatePath)
+		if err != nil {
+			return fmt.Errorf("error reading template: %v", err)
+		}
+		name, text = filepath.Base(templatePath), string(b)
+	}
+
+	data := buildTemplateData(testSuites, jmap, opts)
+	var tmpl interface {
+		Execute(w io.Writer, data interface{}) error
+	}
+	var err error
+	switch filepath.Ext(strings.TrimSuffix(name, ".tmpl")) {
+	case ".html", ".htm":
+		tmpl, err = htmltemplate.New(name).Funcs(templateFuncs(data)).Parse(text)
+	default:
+		tmpl, err = template.New(name).Funcs(templateFuncs(data)).Parse(text)
+	}
+	if err != nil {
+		return fmt.Errorf("error parsing template: %v", err)
+	}
+
+	f, err := os.Create(outPath)
+	if err != nil {
+		return fmt.Errorf("error creating output file: %v", err)
+	}
+	defer f.Close()
+
+	if err := tmpl.Execute(f, data); err != nil {
+		return fmt.Errorf("error rendering template: %v", err)
+	}
+	return nil
+}
+
+// flattenTests lists the tests and their subtests depth first, as the rows
+// of a Markdown table.
+func flattenTests(tests []ReportTest) []templateRow {
+	var rows []templateRow
+	var visit func(tests []ReportTest, prefix string, depth int)
+	visit = func(tests []ReportTest, prefix string, depth int) {
+		for _, test := range tests {
+			rows = append(rows, templateRow{ReportTest: test, Prefix: prefix, Depth: depth})
+			visit(test.Subtests, prefix+test.Name+" → ", depth+1)
+		}
+	}
+	visit(tests, "", 0)
+	return rows
+}
+
+// groupByKind splits tests into one group per kind, in testKinds order,
+// leaving out kinds without tests.
+func groupByKind(tests []ReportTest) []templateGroup {
+	var groups []templateGroup
+	for _, kind := range testKinds {
+		group := templateGroup{Kind: kind, Title: kindSectionTitles[kind]}
+		for _, test := range tests {
+			if test.Kind == kind {
+				group.Tests = append(group.Tests, test)
+			}
+		}
+		if len(group.Tests) > 0 {
+			groups = append(groups, group)
+		}
+	}
+	return groups
+}
+
+// escapeCell keeps s from breaking a Markdown table row, escaping pipes and
+// putting it on a single line. Unlike escapeMarkdown, formatting is kept.
+func escapeCell(s string) string {
+	return strings.NewReplacer("|", "\\|", "\r\n", " ", "\n", " ").Replace(s)
+}
+
+// escapeMarkdown escapes the characters Markdown would format and puts s on
+// a single line, so it can be used as literal text in a table cell.
+func escapeMarkdown(s string) string {
+	return strings.NewReplacer(
+		"\\", "\\\\", "`", "\\`", "*", "\\*", "_", "\\_", "[", "\\[", "]", "\\]",
+		"<", "&lt;", ">", "&gt;", "|", "\\|", "\r\n", " ", "\n", " ",
+	).Replace(s)
+}
+
+// formatDuration formats a duration in seconds for reading, like "0.25s" or
+// "2m5s" from a minute on.
+func formatDuration(seconds float64) string {
+	if seconds < 60 {
+		return formatSeconds(math.Round(seconds*1000) / 1000)
+	}
+	return (time.Duration(math.Round(seconds)) * time.Second).String()
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"go/ast"
+	"go/types"
+	"strings"
+)
+
+/*** testify suites ***/
+
+const testifySuiteRun = "github.com/stretchr/testify/suite.Run"
+
+// isSuiteRun reports whether call is testify's suite.Run(t, suite).
+func (sp *sourcePackage) isSuiteRun(call *ast.CallExpr) bool {
+	return sp != nil && sp.info != nil && len(call.Args) == 2 && sp.calleeName(call) == testifySuiteRun
+}
+
+// CollectSuiteTests lists the Test* methods of the suite passed to testify's
+// suite.Run, in the order testify runs them. Each method is a subtest of the
+// test calling suite.Run, and s.Run calls inside it are its subtests.
+func CollectSuiteTests(pkg *sourcePackage, call *ast.CallExpr, parentName string, expandedVariables []ExpandedVar) []TestUnit {
+	tests := make([]TestUnit, 0)
+
+	suiteType := pkg.info.TypeOf(call.Args[1])
+	if suiteType == nil {
+		return tests
+	}
+
+	// Method sets are sorted by name, like the reflection testify uses
+	methods := types.NewMethodSet(suiteType)
+	for i := 0; i < methods.Len(); i++ {
+		fn, ok := methods.At(i).Obj().(*types.Func)
+		if !ok || !strings.HasPrefix(fn.Name(), "Test") {
+			continue
+		}
+
+		machineName := pkg.subtestName(parentName, fn.Name())
+		unit := TestUnit{
+			MachineTestName: machineName,
+			TestName:        fn.Name(),
+		}
+		if fd := pkg.declOf(fn); fd != nil {
+			unit.File, unit.Line = pkg.position(fd.Pos())
+			astFile := pkg.fileAt(fd.Pos())
+			file := pkg.fset.File(fd.Pos())
+			unit.CommentHeader = FindRelativeComment(fd.Pos(), astFile.Comments, file, file.Name())
+			unit.Subtests = pkg.collectFromDecl(fd, nil, machineName, expandedVariables)
+		}
+		tests = append(tests, unit)
+	}
+	return tests
+}
@@ -0,0 +1,285 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+/*** Requirements traceability ***/
+
+// Requirement is an entry of an external requirement list.
+type Requirement struct {
+	ID          string `yaml:"id"`
+	Description string `yaml:"description"`
+}
+
+// RequirementTrace is a requirement with the tests covering it, that is the
+// tests tagged "@requirement: <ID>", and their aggregated status.
+type RequirementTrace struct {
+	Requirement
+	Listed bool         // the requirement is in the external requirement list
+	Status string       // PASS if any test passed, else FLAKY, ERROR, FAIL, SKIP or NOT RUN; UNTESTED without tests
+	Tests  []tracedTest // in report order
+}
+
+// Verified reports whether at least one test covering the requirement passed.
+func (rt RequirementTrace) Verified() bool { return rt.Status == "PASS" }
+
+type tracedTest struct {
+	Path   string // "TestParent → child" as in the report's Test Path column
+	Unit   TestUnit
+	Status string
+}
+
+// ParseRequirements reads a requirement list from a CSV or YAML file, chosen
+// by extension. CSV files have the requirement ID in the first column and an
+// optional description in the second, with an optional header row. YAML files
+// hold a list of IDs, a list of {id, description} objects, or a map of ID to
+// description.
+func ParseRequirements(path string) ([]Requirement, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		return parseRequirementsCSV(f)
+	case ".yaml", ".yml":
+		return parseRequirementsYAML(f)
+	default:
+		return nil, fmt.Errorf("unsupported requirement list %s: expected .csv, .yaml or .yml", path)
+	}
+}
+
+func parseRequirementsCSV(r io.Reader) ([]Requirement, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+	rows, err := cr.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse requirement CSV: %v", err)
+	}
+
+	var reqs []Requirement
+	for i, row := range rows {
+		if len(row) == 0 || strings.TrimSpace(row[0]) == "" {
+			continue
+		}
+		id := strings.TrimSpace(row[0])
+		if i == 0 && (strings.EqualFold(id, "id") || strings.EqualFold(id, "requirement")) {
+			continue // header row
+		}
+		req := Requirement{ID: id}
+		if len(row) > 1 {
+			req.Description = strings.TrimSpace(row[1])
+		}
+		reqs = append(reqs, req)
+	}
+	return reqs, nil
+}
+
+func parseRequirementsYAML(r io.Reader) ([]Requirement, error) {
+	var doc yaml.Node
+	if err := yaml.NewDecoder(r).Decode(&doc); err != nil {
+		if err == io.EOF {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to parse requirement YAML: %v", err)
+	}
+	if len(doc.Content) == 0 {
+		return nil, nil
+	}
+
+	var reqs []Requirement
+	switch root := doc.Content[0]; root.Kind {
+	case yaml.SequenceNode:
+		for _, item := range root.Content {
+			var req Requirement
+			if item.Kind == yaml.ScalarNode {
+				req.ID = item.Value
+			} else if err := item.Decode(&req); err != nil {
+				return nil, fmt.Errorf("failed to parse requirement YAML: %v", err)
+			}
+			if req.ID != "" {
+				reqs = append(reqs, req)
+			}
+		}
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(root.Content); i += 2 {
+			reqs = append(reqs, Requirement{ID: root.Content[i].Value, Description: root.Content[i+1].Value})
+		}
+	default:
+		return nil, fmt.Errorf("failed to parse requirement YAML: expected a list or map of requirements")
+	}
+	return reqs, nil
+}
+
+// requirementIDs splits the values of a unit's @requirement tags, which may
+// list several IDs separated by commas or spaces.
+func requirementIDs(tu TestUnit) []string {
+	var ids []string
+	for _, value := range tu.Tags["requirement"] {
+		ids = append(ids, strings.FieldsFunc(value, func(r rune) bool {
+			return r == ',' || r == ' ' || r == '\t'
+		})...)
+	}
+	return ids
+}
+
+// BuildTraceability inverts the test tree into one RequirementTrace per
+// requirement, covering both the requirements in the list, in list order,
+// and those only found in tags, sorted by ID after them.
+func BuildTraceability(testSuites []TestSuite, jmap map[string]junitRecord, requirements []Requirement) []RequirementTrace {
+	traces := make(map[string]*RequirementTrace)
+	var order []string
+	for _, req := range requirements {
+		if _, ok := traces[req.ID]; ok {
+			continue
+		}
+		traces[req.ID] = &RequirementTrace{Requirement: req, Listed: true}
+		order = append(order, req.ID)
+	}
+
+	var tagged []string
+	var visit func(tu TestUnit, pkgName, pathPrefix string)
+	visit = func(tu TestUnit, pkgName, pathPrefix string) {
+		path := tu.TestName
+		if pathPrefix != "" {
+			path = pathPrefix + " → " + tu.TestName
+		}
+
+		status := "NOT RUN"
+		if rec, ok := lookupRecord(tu, pkgName, jmap); ok {
+			status = rec.Status
+		}
+		for _, id := range requirementIDs(tu) {
+			trace, ok := traces[id]
+			if !ok {
+				trace = &RequirementTrace{Requirement: Requirement{ID: id}}
+				traces[id] = trace
+				tagged = append(tagged, id)
+			}
+			trace.Tests = append(trace.Tests, tracedTest{Path: path, Unit: tu, Status: status})
+		}
+
+		for _, sub := range tu.Subtests {
+			visit(sub, pkgName, path)
+		}
+	}
+	for _, ts := range testSuites {
+		for _, tu := range ts.TestUnits {
+			visit(tu, ts.PackageName, "")
+		}
+	}
+
+	sort.Strings(tagged)
+	out := make([]RequirementTrace, 0, len(order)+len(tagged))
+	for _, id := range append(order, tagged...) {
+		trace := traces[id]
+		trace.Status = aggregateStatus(trace.Tests)
+		out = append(out, *trace)
+	}
+	return out
+}
+
+// aggregateStatus is the status of a requirement given its tests: PASS if any
+// of them passed, otherwise the first of FLAKY, ERROR, FAIL, SKIP and NOT RUN.
+func aggregateStatus(tests []tracedTest) string {
+	if len(tests) == 0 {
+		return "UNTESTED"
+	}
+	seen := make(map[string]bool)
+	for _, test := range tests {
+		seen[test.Status] = true
+	}
+	for _, status := range []string{"PASS", "FLAKY", "ERROR", "FAIL", "SKIP"} {
+		if seen[status] {
+			return status
+		}
+	}
+	return "NOT RUN"
+}
+
+// GenerateTraceabilityReport writes the requirement → tests matrix in place
+// of the test documentation, followed by the requirements no passing test
+// verifies and, with a requirement list, those no test covers at all.
+func GenerateTraceabilityReport(testSuites []TestSuite, jmap map[string]junitRecord, requirements []Requirement, outPath string, opts ReportOptions) error {
+	f, err := os.Create(outPath)
+	if err != nil {
+		return fmt.Errorf("error creating output file: %v", err)
+	}
+	defer f.Close()
+
+	if abs, err := filepath.Abs(outPath); err == nil {
+		opts.reportDir = filepath.Dir(abs)
+	}
+
+	w := func(format string, a ...interface{}) {
+		fmt.Fprintf(f, format, a...)
+	}
+
+	traces := BuildTraceability(testSuites, jmap, requirements)
+
+	w("# Requirements Traceability Matrix\n\n")
+	if len(traces) == 0 {
+		w("No requirements found. Tag tests with `@requirement: <ID>` to trace them.\n")
+		return nil
+	}
+
+	w("| Requirement | Status | Description | Tests |\n")
+	w("|-------------|--------|-------------|-------|\n")
+	var unverified, untested []RequirementTrace
+	for _, trace := range traces {
+		var tests []string
+		for _, test := range trace.Tests {
+			path := test.Path
+			if link := sourceLink(test.Unit, opts); link != "" {
+				path = fmt.Sprintf("[%s](%s)", escapeLinkText(test.Path), link)
+			}
+			tests = append(tests, fmt.Sprintf("%s %s", getStatusIcon(test.Status), path))
+		}
+		w("| %s | %s %s | %s | %s |\n",
+			escapeLinkText(trace.ID), getStatusIcon(trace.Status), trace.Status,
+			strings.ReplaceAll(trace.Description, "|", "\\|"), strings.Join(tests, "<br>"))
+
+		switch {
+		case len(trace.Tests) == 0:
+			untested = append(untested, trace)
+		case !trace.Verified():
+			unverified = append(unverified, trace)
+		}
+	}
+	w("\n")
+
+	if len(unverified) > 0 {
+		w("## ⚠️ Unverified Requirements\n\n")
+		w("No test covering these requirements passed.\n\n")
+		for _, trace := range unverified {
+			w("- **%s** (%s)\n", trace.ID, trace.Status)
+		}
+		w("\n")
+	}
+
+	if len(untested) > 0 {
+		w("## ⚠️ Requirements Without Tests\n\n")
+		for _, trace := range untested {
+			if trace.Description != "" {
+				w("- **%s**: %s\n", trace.ID, trace.Description)
+			} else {
+				w("- **%s**\n", trace.ID)
+			}
+		}
+		w("\n")
+	}
+
+	return nil
+}
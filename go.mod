@@ -1,9 +1,17 @@
 module github.com/wleev/go-test-doc-action
 
-go 1.24.4
+go 1.26.0
 
+// golang.org/x/tools v0.50.0, which requires go 1.26, is the oldest release
+// tested whose go/packages loads type-checked packages under current Go
+// toolchains: with v0.38.0 and v0.42.0 loading fails with "internal error:
+// package "fmt" without types was imported" under Go 1.27.
 require (
-	golang.org/x/mod v0.29.0 // indirect
-	golang.org/x/sync v0.17.0 // indirect
-	golang.org/x/tools v0.38.0 // indirect
+	golang.org/x/tools v0.50.0
+	gopkg.in/yaml.v3 v3.0.1
+)
+
+require (
+	golang.org/x/mod v0.41.0 // indirect
+	golang.org/x/sync v0.23.0 // indirect
 )